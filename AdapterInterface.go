@@ -10,17 +10,48 @@ type AdapterInterface interface {
 	Ping() error
 
 	// Query runs a query and return the result.
+	//
+	// Named parameters in the query look like ?name and take their values from params.
+	// A parameter having a slice value is expanded to a list of values, which is useful with IN clauses.
+	//
+	// Statements that produce rows, like selects and statements having a RETURNING clause, return those rows.
+	// Other statements return the number of affected rows and the last insert id.
 	Query(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error)
 
+	// QueryEach runs a query and calls fn with each row of the result one at a time,
+	// without holding the whole result in memory.
+	//
+	// Iteration stops at the first error returned by fn and that error is returned.
+	// Inside a transaction fn should not run other queries, since the connection is busy until all rows are read.
+	QueryEach(ctx context.Context, query string, params map[string]interface{}, fn func(row map[string]interface{}) error) error
+
 	// QueryBulk runs a query using an array of parameters and return the combined result.
 	//
 	// This query is intended to do bulk INSERTS, UPDATES and DELETES.
 	// Using this for SELECTS will result in an error.
+	//
+	// The ids generated for each set of parameters are returned in the same order as params as last_insert_ids.
+	// This is a []int64 for MySQL and SQLite, and a []interface{} holding the first column returned by
+	// a RETURNING clause for Postgres, where a set of parameters that returns no rows has a nil entry.
 	QueryBulk(ctx context.Context, query string, params []map[string]interface{}) ([]map[string]interface{}, error)
 
 	// WrapInTx runs the content of the function in a single transaction.
 	WrapInTx(ctx context.Context, fn func(ctx context.Context) (interface{}, error)) (interface{}, error)
 
+	// WrapInTxWithOptions runs the content of the function in a single transaction started using opts.
+	//
+	// The default options of the database are used when opts is nil.
+	// A nested call requesting a stricter isolation level than the enclosing transaction,
+	// or a read only transaction inside one that is not, results in an error.
+	WrapInTxWithOptions(ctx context.Context, opts *TxOptions, fn func(ctx context.Context) (interface{}, error)) (interface{}, error)
+
+	// WrapInTxWithRetry runs the content of the function in a single transaction started using opts,
+	// running it again when the transaction fails due to a serialization failure or a deadlock.
+	//
+	// Only the outermost transaction is retried. A nested call behaves the same as WrapInTxWithOptions().
+	// Use TxAttempt() to get the attempt number inside the function.
+	WrapInTxWithRetry(ctx context.Context, opts *TxOptions, policy RetryPolicy, fn func(ctx context.Context) (interface{}, error)) (interface{}, error)
+
 	// Destruct will close the database adapter releasing all resources.
 	Destruct() error
 }
@@ -0,0 +1,20 @@
+package db
+
+import (
+	"context"
+)
+
+// BatchInsertInterface is implemented by database adapters that can insert many rows using a single statement.
+//
+// Not all adapters implement this. Use a type assertion on the adapter to check whether it is supported.
+type BatchInsertInterface interface {
+	// InsertBatch runs a single row insert query for an array of parameters and return the combined result.
+	//
+	// The row of values of the query is repeated to insert as many rows as the database allows in a single statement,
+	// which is much faster than running the query once for each set of parameters as QueryBulk() does.
+	// Named parameters are only allowed in the row of values.
+	//
+	// The result is the same as that of QueryBulk(), except that rows an insert skips return no id,
+	// so last_insert_ids may then be shorter than params. Wrap the call in a transaction to make all inserts atomic.
+	InsertBatch(ctx context.Context, query string, params []map[string]interface{}) ([]map[string]interface{}, error)
+}
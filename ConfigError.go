@@ -0,0 +1,19 @@
+package db
+
+import (
+	"strings"
+)
+
+// ConfigError is returned when the configuration of an adapter is not valid. It lists all the problems found at once.
+type ConfigError struct {
+	// Adapter is the name of the adapter, like mysql-adapter.
+	Adapter string
+
+	// Problems describe each invalid setting, like `port should be between 1 and 65535, got 0`.
+	Problems []string
+}
+
+// Error returns all the problems in a single line.
+func (e *ConfigError) Error() string {
+	return e.Adapter + ": " + strings.Join(e.Problems, "; ")
+}
@@ -0,0 +1,17 @@
+package db
+
+import (
+	"context"
+)
+
+// CopyFromInterface is implemented by database adapters that can load rows in to a table using COPY FROM.
+//
+// Not all adapters implement this. Use a type assertion on the adapter to check whether it is supported.
+type CopyFromInterface interface {
+	// CopyFrom loads rows in to the columns of table and return the number of loaded rows as the affected rows.
+	//
+	// Each row should have a value for each of the columns in the same order.
+	// The rows are loaded in the transaction attached to the context,
+	// or in a transaction of its own when there is none, so that either all rows are loaded or none.
+	CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) ([]map[string]interface{}, error)
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"context"
+	"io"
+)
+
+// LoadDataInterface is implemented by database adapters that can stream data in to a table using LOAD DATA LOCAL INFILE.
+//
+// Loading runs in the transaction attached to the context, or in a transaction of its own when there is none,
+// so either all rows are loaded or none.
+//
+// Not all adapters implement this. Use a type assertion on the adapter to check whether it is supported.
+type LoadDataInterface interface {
+	// LoadData streams comma separated data read from r in to the columns of table
+	// and return the number of loaded rows as the affected rows.
+	//
+	// The data should be in the format written by LoadRows(). All columns of the table are loaded when columns is empty.
+	LoadData(ctx context.Context, table string, columns []string, r io.Reader) ([]map[string]interface{}, error)
+
+	// LoadRows streams the rows returned by next in to the columns of table
+	// and return the number of loaded rows as the affected rows.
+	//
+	// next is called until it returns io.EOF. Any other error stops loading and is returned.
+	LoadRows(ctx context.Context, table string, columns []string, next func() ([]interface{}, error)) ([]map[string]interface{}, error)
+}
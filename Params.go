@@ -0,0 +1,59 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+// Params creates named parameters from the fields of the struct v, or of the struct v points to.
+//
+// Each field is added under the name given by its `db:"name"` tag, the same way QueryInto() maps columns to fields.
+// Fields of a nil embedded struct pointer are left out. Running a query having a named parameter
+// without a matching field results in the same missing parameter error as for a map.
+func Params(v interface{}) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("db: need a struct to create parameters, got nil %T", v)
+		}
+
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct || !internal.IsStruct(rv.Type()) {
+		return nil, fmt.Errorf("db: need a struct to create parameters, got %T", v)
+	}
+
+	fields := internal.Fields(rv.Type())
+	params := make(map[string]interface{}, len(fields))
+
+	for name, index := range fields {
+		f, ok := internal.LookupField(rv, index)
+		if !ok {
+			continue
+		}
+
+		params[name] = f.Interface()
+	}
+
+	return params, nil
+}
+
+// BulkParams creates named parameters for QueryBulk() from each struct in vs the same way Params() does.
+func BulkParams[T any](vs []T) ([]map[string]interface{}, error) {
+	params := make([]map[string]interface{}, len(vs))
+
+	for i, v := range vs {
+		p, err := Params(v)
+		if err != nil {
+			return nil, err
+		}
+
+		params[i] = p
+	}
+
+	return params, nil
+}
@@ -0,0 +1,87 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/kosatnkn/db"
+	"github.com/kosatnkn/db/dbtest"
+)
+
+// TestParams tests creating named parameters from a struct.
+func TestParams(t *testing.T) {
+	nick := "one"
+	s := Sample{
+		Base:     Base{ID: 1},
+		Name:     "Name 1",
+		Nick:     &nick,
+		Age:      21,
+		Password: "pwd1",
+	}
+
+	params, err := db.Params(&s)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "1, Name 1, one, 21, false"
+	got := fmt.Sprintf("%v, %v, %v, %v, %v", params["id"], params["name"], *params["nick"].(*string), params["age"], params["active"])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	if _, ok := params["password"]; ok {
+		t.Errorf("Need `password` to be left out")
+	}
+
+	// not a struct
+	_, err = db.Params(map[string]interface{}{})
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	eNeed := "db: need a struct to create parameters, got map[string]interface {}"
+	eGot := err.Error()
+	if eGot != eNeed {
+		t.Errorf("Need `%s`, got `%s`", eNeed, eGot)
+	}
+}
+
+// TestBulkParams tests creating named parameters from a slice of structs.
+func TestBulkParams(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	q := `insert into sample(name, age) values (?name, ?age)`
+	adapter.Expect(q)
+
+	params, err := db.BulkParams([]Sample{
+		{Name: "Name 1", Age: 21},
+		{Name: "Name 2", Age: 22},
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if _, err := adapter.QueryBulk(context.Background(), q, params); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "Name 2, 22"
+	got := fmt.Sprintf("%v, %v", params[1]["name"], params[1]["age"])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// a parameter without a matching field
+	_, err = adapter.QueryBulk(context.Background(), `insert into sample(name, password) values (?name, ?password)`, params)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	eNeed := "dbtest: parameter 'password' is missing"
+	eGot := err.Error()
+	if eGot != eNeed {
+		t.Errorf("Need `%s`, got `%s`", eNeed, eGot)
+	}
+}
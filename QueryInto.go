@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+// errFound is used to stop iterating once the first row is found.
+var errFound = errors.New("found")
+
+// QueryInto runs a query and maps each row of the result to a T.
+//
+// When T is a struct, or a pointer to one, its fields are set from the columns named by their `db:"name"` tags.
+// Fields of embedded structs are set as if they belong to T. Columns without a matching field are ignored.
+// A NULL sets a field to its zero value, so use a pointer field or a sql.Scanner like sql.NullString
+// to tell a NULL apart from a zero value.
+//
+// Any other T, like int or time.Time, is set from the only column of each row.
+func QueryInto[T any](ctx context.Context, adapter AdapterInterface, query string, params map[string]interface{}) ([]T, error) {
+	result := make([]T, 0)
+
+	err := adapter.QueryEach(ctx, query, params, func(row map[string]interface{}) error {
+		var v T
+		if err := scanRow(row, reflect.ValueOf(&v).Elem()); err != nil {
+			return err
+		}
+
+		result = append(result, v)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// QueryOne runs a query and maps the first row of the result to a T the same way QueryInto() does.
+//
+// sql.ErrNoRows is returned when the result has no rows.
+func QueryOne[T any](ctx context.Context, adapter AdapterInterface, query string, params map[string]interface{}) (T, error) {
+	var v T
+
+	err := adapter.QueryEach(ctx, query, params, func(row map[string]interface{}) error {
+		if err := scanRow(row, reflect.ValueOf(&v).Elem()); err != nil {
+			return err
+		}
+
+		return errFound
+	})
+	if errors.Is(err, errFound) {
+		return v, nil
+	}
+	if err != nil {
+		return v, err
+	}
+
+	return v, sql.ErrNoRows
+}
+
+// scanRow sets dest using the columns of the row.
+func scanRow(row map[string]interface{}, dest reflect.Value) error {
+	if !internal.IsStruct(dest.Type()) {
+		if len(row) != 1 {
+			return fmt.Errorf("db: need 1 column to set a %s, got %d", dest.Type(), len(row))
+		}
+
+		for col, value := range row {
+			if err := internal.Assign(dest, value); err != nil {
+				return fmt.Errorf("db: column '%s': %v", col, err)
+			}
+		}
+
+		return nil
+	}
+
+	if dest.Kind() == reflect.Pointer {
+		dest.Set(reflect.New(dest.Type().Elem()))
+		dest = dest.Elem()
+	}
+
+	fields := internal.Fields(dest.Type())
+
+	for col, value := range row {
+		index, ok := fields[col]
+		if !ok {
+			continue
+		}
+
+		if err := internal.Assign(internal.FieldByIndex(dest, index), value); err != nil {
+			return fmt.Errorf("db: column '%s': %v", col, err)
+		}
+	}
+
+	return nil
+}
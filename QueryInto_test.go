@@ -0,0 +1,183 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kosatnkn/db"
+	"github.com/kosatnkn/db/dbtest"
+)
+
+// Base holds the columns common to all records.
+type Base struct {
+	ID      int64     `db:"id"`
+	Created time.Time `db:"created_at"`
+}
+
+// Sample is a record of the sample table.
+type Sample struct {
+	Base
+	Name     string         `db:"name"`
+	Nick     *string        `db:"nick"`
+	Email    sql.NullString `db:"email"`
+	Age      int            `db:"age"`
+	Active   bool           `db:"active"`
+	Password string         `db:"-"`
+}
+
+// TestQueryInto tests mapping of rows to structs.
+func TestQueryInto(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	q := `select * from sample`
+	adapter.Expect(q).WillReturn([]map[string]interface{}{
+		{
+			"id":         int64(1),
+			"created_at": created,
+			"name":       []byte("Name 1"),
+			"nick":       []byte("one"),
+			"email":      "one@sample.com",
+			"age":        []byte("21"),
+			"active":     int64(1),
+			"password":   "pwd1",
+			"unknown":    "ignored",
+		},
+		{
+			"id":         int64(2),
+			"created_at": created,
+			"name":       "Name 2",
+			"nick":       nil,
+			"email":      nil,
+			"age":        int64(22),
+			"active":     false,
+		},
+	})
+
+	r, err := db.QueryInto[Sample](context.Background(), adapter, q, nil)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("Need 2 records, got %d records", len(r))
+	}
+
+	need := "1, 2023-01-02, Name 1, one, one@sample.com, 21, true, "
+	got := fmt.Sprintf("%d, %s, %s, %s, %s, %d, %v, %s",
+		r[0].ID, r[0].Created.Format("2006-01-02"), r[0].Name, *r[0].Nick, r[0].Email.String, r[0].Age, r[0].Active, r[0].Password)
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	need = "2, Name 2, <nil>, false, 22, false"
+	got = fmt.Sprintf("%d, %s, %v, %v, %d, %v", r[1].ID, r[1].Name, r[1].Nick, r[1].Email.Valid, r[1].Age, r[1].Active)
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// pointers to structs
+	pr, err := db.QueryInto[*Sample](context.Background(), adapter, q, nil)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(pr) != 2 || pr[1].Name != "Name 2" {
+		t.Errorf("Need 2 records, got %v", pr)
+	}
+}
+
+// TestQueryIntoValue tests mapping of single column rows to values.
+func TestQueryIntoValue(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	q := `select id from sample`
+	adapter.Expect(q).WillReturn([]map[string]interface{}{
+		{"id": int64(1)},
+		{"id": []byte("2")},
+	})
+
+	r, err := db.QueryInto[int](context.Background(), adapter, q, nil)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "[1 2]"
+	got := fmt.Sprintf("%v", r)
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestQueryIntoFail tests mapping of values that cannot be converted.
+func TestQueryIntoFail(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	q := `select * from sample`
+	adapter.Expect(q).WillReturn([]map[string]interface{}{
+		{"age": "not a number"},
+	})
+
+	_, err := db.QueryInto[Sample](context.Background(), adapter, q, nil)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := "db: column 'age': "
+	if !strings.HasPrefix(err.Error(), need) {
+		t.Errorf("Need `%s...`, got `%s`", need, err.Error())
+	}
+
+	// overflow
+	adapter = dbtest.NewAdapter()
+	adapter.Expect(q).WillReturn([]map[string]interface{}{
+		{"age": int64(300)},
+	})
+
+	_, err = db.QueryInto[struct {
+		Age int8 `db:"age"`
+	}](context.Background(), adapter, q, nil)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need = "db: column 'age': value 300 overflows int8"
+	if err.Error() != need {
+		t.Errorf("Need `%s`, got `%s`", need, err.Error())
+	}
+}
+
+// TestQueryOne tests mapping of the first row.
+func TestQueryOne(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	q := `select * from sample where id = ?id`
+	adapter.Expect(q).WillReturn([]map[string]interface{}{
+		{"id": int64(1), "name": "Name 1"},
+		{"id": int64(2), "name": "Name 2"},
+	})
+
+	r, err := db.QueryOne[Sample](context.Background(), adapter, q, map[string]interface{}{"id": 1})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "1, Name 1"
+	got := fmt.Sprintf("%d, %s", r.ID, r.Name)
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// no rows
+	q = `select * from sample where id = 0`
+	adapter.Expect(q).WillReturn(nil)
+
+	_, err = db.QueryOne[Sample](context.Background(), adapter, q, nil)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Need `%v`, got `%v`", sql.ErrNoRows, err)
+	}
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+// RetryPolicy controls how many times and how often a transaction that failed
+// due to a serialization failure or a deadlock is retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times the transaction is run, including the first attempt.
+	// The transaction is run only once when this is less than 2.
+	MaxAttempts int
+
+	// BaseDelay is the upper bound of the random wait before the first retry.
+	// The upper bound doubles with each subsequent retry.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the upper bound of the random wait between retries. There is no cap when this is zero.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is a retry policy suitable for most short running transactions.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    time.Second,
+}
+
+// TxAttempt returns the attempt number of the transaction retried by WrapInTxWithRetry().
+//
+// The first attempt is 1. Returns 0 when the context does not belong to a retried transaction.
+func TxAttempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(internal.AttemptKey).(int)
+
+	return attempt
+}
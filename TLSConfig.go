@@ -0,0 +1,54 @@
+package db
+
+import (
+	"fmt"
+)
+
+// TLS modes, named after the sslmode values of Postgres.
+const (
+	// TLSDisable connects without TLS.
+	TLSDisable = "disable"
+	// TLSRequire connects using TLS without verifying the certificate of the server.
+	// As with libpq, the certificate is verified the same way as TLSVerifyCA when a CA certificate is set.
+	TLSRequire = "require"
+	// TLSVerifyCA connects using TLS and verifies that the certificate of the server is signed by a trusted CA.
+	TLSVerifyCA = "verify-ca"
+	// TLSVerifyFull connects using TLS and verifies that the certificate of the server is signed by a trusted CA
+	// and issued for the host connected to.
+	TLSVerifyFull = "verify-full"
+)
+
+// TLSConfig contains the settings used to connect to a database over TLS.
+type TLSConfig struct {
+	// Mode is one of disable, require, verify-ca or verify-full. TLS is disabled when this is not set.
+	Mode string `yaml:"mode"`
+
+	// CACert is the path to the PEM encoded certificate of the CA that signed the certificate of the server.
+	// The CAs of the system are trusted when this is not set.
+	CACert string `yaml:"ca_cert"`
+
+	// Cert and Key are the paths to the PEM encoded certificate and private key used to authenticate the client.
+	// Both should be set to use client authentication.
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+}
+
+// Enabled tells whether connections should use TLS.
+func (c TLSConfig) Enabled() bool {
+	return c.Mode != "" && c.Mode != TLSDisable
+}
+
+// Validate checks whether the TLS settings are valid.
+func (c TLSConfig) Validate() error {
+	switch c.Mode {
+	case "", TLSDisable, TLSRequire, TLSVerifyCA, TLSVerifyFull:
+	default:
+		return fmt.Errorf("tls mode '%s' is not one of disable, require, verify-ca or verify-full", c.Mode)
+	}
+
+	if (c.Cert == "") != (c.Key == "") {
+		return fmt.Errorf("tls cert and key should be set together")
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TxError is returned by WrapInTx() when committing or rolling back a transaction fails.
+//
+// errors.Is() and errors.As() match both the error returned by the wrapped function
+// and the error returned when finishing the transaction.
+type TxError struct {
+	// Err is the error returned by the wrapped function. This is nil when the function succeeded
+	// but committing the transaction failed.
+	Err error
+
+	// TxErr is the error returned when committing or rolling back the transaction.
+	TxErr error
+}
+
+// Error returns the combined error message.
+func (e *TxError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("commit failed: %v", e.TxErr)
+	}
+
+	return fmt.Sprintf("%v (rollback failed: %v)", e.Err, e.TxErr)
+}
+
+// Unwrap returns the error returned by the wrapped function, or the transaction error when there is none.
+func (e *TxError) Unwrap() error {
+	if e.Err == nil {
+		return e.TxErr
+	}
+
+	return e.Err
+}
+
+// Is reports whether the transaction error matches target.
+//
+// The error returned by the wrapped function is matched through Unwrap().
+func (e *TxError) Is(target error) bool {
+	return errors.Is(e.TxErr, target)
+}
+
+// As finds the first error in the chain of the transaction error that matches target.
+//
+// The error returned by the wrapped function is matched through Unwrap().
+func (e *TxError) As(target interface{}) bool {
+	return errors.As(e.TxErr, target)
+}
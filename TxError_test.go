@@ -0,0 +1,51 @@
+package db_test
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/kosatnkn/db"
+)
+
+// TestTxErrorRollback tests matching of errors when rolling back fails.
+func TestTxErrorRollback(t *testing.T) {
+	fnErr := errors.New("function failed")
+
+	var err error = &db.TxError{Err: fnErr, TxErr: sql.ErrConnDone}
+
+	if !errors.Is(err, fnErr) {
+		t.Errorf("Need error to match function error")
+	}
+	if !errors.Is(err, sql.ErrConnDone) {
+		t.Errorf("Need error to match transaction error")
+	}
+
+	need := "function failed (rollback failed: sql: connection is already closed)"
+	got := err.Error()
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestTxErrorCommit tests matching of errors when committing fails.
+func TestTxErrorCommit(t *testing.T) {
+	var err error = &db.TxError{TxErr: sql.ErrTxDone}
+
+	var txErr *db.TxError
+	if !errors.As(err, &txErr) {
+		t.Fatalf("Need error to be a TxError")
+	}
+	if txErr.Err != nil {
+		t.Errorf("Need nil function error, got %v", txErr.Err)
+	}
+	if !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("Need error to match transaction error")
+	}
+
+	need := "commit failed: sql: transaction has already been committed or rolled back"
+	got := err.Error()
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
@@ -0,0 +1,19 @@
+package db
+
+import (
+	"database/sql"
+)
+
+// TxOptions holds the options used to start a transaction.
+type TxOptions struct {
+	// Isolation is the isolation level of the transaction.
+	// The default isolation level of the database is used when this is sql.LevelDefault.
+	Isolation sql.IsolationLevel
+
+	// ReadOnly starts a transaction that cannot modify data.
+	ReadOnly bool
+
+	// Deferrable starts a transaction that waits for a snapshot free of serialization failures before it runs.
+	// This is only supported by Postgres and only takes effect in SERIALIZABLE READ ONLY transactions.
+	Deferrable bool
+}
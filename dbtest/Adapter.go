@@ -0,0 +1,356 @@
+// Package dbtest provides an in-memory fake of db.AdapterInterface to be used in unit tests.
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kosatnkn/db"
+	"github.com/kosatnkn/db/internal"
+)
+
+// Context key type to be used with contexts.
+type key string
+
+// txKey is the key used to mark a context as being inside a transaction.
+const txKey key = "tx"
+
+// ErrRetryable is an error that makes WrapInTxWithRetry() run the transaction again.
+//
+// Use it with Expectation.WillFail() to simulate serialization failures and deadlocks.
+var ErrRetryable = errors.New("dbtest: retryable error")
+
+// Method names recorded in calls.
+const (
+	MethodQuery       string = "Query"
+	MethodQueryEach   string = "QueryEach"
+	MethodQueryBulk   string = "QueryBulk"
+	MethodInsertBatch string = "InsertBatch"
+)
+
+// Call is a record of a single call made to the adapter.
+type Call struct {
+	Method string
+	// Query is the normalized query.
+	Query string
+	// Params contains the parameters passed to Query() and QueryEach().
+	Params map[string]interface{}
+	// BulkParams contains the parameters passed to QueryBulk() and InsertBatch().
+	BulkParams []map[string]interface{}
+	// InTx tells whether the call was made inside a transaction.
+	InTx bool
+}
+
+// Expectation is a query registered with the adapter along with the result it should produce.
+type Expectation struct {
+	query  string
+	result []map[string]interface{}
+	err    error
+}
+
+// WillReturn sets the result returned when the expected query is run.
+func (e *Expectation) WillReturn(result []map[string]interface{}) *Expectation {
+	e.result = result
+	return e
+}
+
+// WillFail sets the error returned when the expected query is run.
+func (e *Expectation) WillFail(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Adapter is a fake database adapter that returns canned results for registered queries.
+//
+// Queries are matched after normalization, so differences in whitespace between
+// the registered query and the query that is run does not matter.
+// Same as the real adapters, running a query with a named parameter missing from params results in an error.
+type Adapter struct {
+	// PingErr is returned by Ping().
+	PingErr error
+
+	// CommitErr is the error to fail committing transactions with.
+	// Same as the real adapters, this is returned wrapped in a db.TxError.
+	CommitErr error
+
+	mu           sync.Mutex
+	expectations []*Expectation
+	calls        []Call
+	begins       int
+	commits      int
+	rollbacks    int
+	destructed   bool
+}
+
+// NewAdapter creates a new fake adapter instance.
+func NewAdapter() *Adapter {
+	return &Adapter{}
+}
+
+// Expect registers a query that is expected to be run.
+//
+// A registered query can be run any number of times.
+// When the same query is registered more than once the first registration wins.
+func (a *Adapter) Expect(query string) *Expectation {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	e := &Expectation{query: normalize(query)}
+	a.expectations = append(a.expectations, e)
+
+	return e
+}
+
+// Calls returns all calls made to Query(), QueryEach(), QueryBulk() and InsertBatch() in the order they were made.
+func (a *Adapter) Calls() []Call {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	calls := make([]Call, len(a.calls))
+	copy(calls, a.calls)
+
+	return calls
+}
+
+// Begins returns the number of transactions started.
+func (a *Adapter) Begins() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.begins
+}
+
+// Commits returns the number of transactions committed.
+func (a *Adapter) Commits() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.commits
+}
+
+// Rollbacks returns the number of transactions rolled back.
+func (a *Adapter) Rollbacks() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.rollbacks
+}
+
+// Destructed tells whether Destruct() has been called.
+func (a *Adapter) Destructed() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.destructed
+}
+
+// Reset clears all registered expectations, recorded calls and transaction counters.
+func (a *Adapter) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.expectations = nil
+	a.calls = nil
+	a.begins = 0
+	a.commits = 0
+	a.rollbacks = 0
+	a.destructed = false
+}
+
+// Ping checks wether the database is accessible.
+func (a *Adapter) Ping() error {
+	return a.PingErr
+}
+
+// Query runs a query and returns the result.
+func (a *Adapter) Query(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	return a.run(ctx, Call{
+		Method: MethodQuery,
+		Query:  normalize(query),
+		Params: params,
+	})
+}
+
+// QueryEach runs a query and calls fn with each row of the result one at a time.
+//
+// Iteration stops at the first error returned by fn and that error is returned.
+func (a *Adapter) QueryEach(ctx context.Context, query string, params map[string]interface{}, fn func(row map[string]interface{}) error) error {
+	rows, err := a.run(ctx, Call{
+		Method: MethodQueryEach,
+		Query:  normalize(query),
+		Params: params,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// QueryBulk runs a query using an array of parameters and return the combined result.
+func (a *Adapter) QueryBulk(ctx context.Context, query string, params []map[string]interface{}) ([]map[string]interface{}, error) {
+	return a.run(ctx, Call{
+		Method:     MethodQueryBulk,
+		Query:      normalize(query),
+		BulkParams: params,
+	})
+}
+
+// InsertBatch runs a single row insert query for an array of parameters and return the combined result.
+//
+// Same as the real adapters, a query that cannot be batched results in an error.
+func (a *Adapter) InsertBatch(ctx context.Context, query string, params []map[string]interface{}) ([]map[string]interface{}, error) {
+	if _, err := internal.ParseBatchQuery(query, internal.SQLite); err != nil {
+		return nil, fmt.Errorf("dbtest: %v", err)
+	}
+
+	return a.run(ctx, Call{
+		Method:     MethodInsertBatch,
+		Query:      normalize(query),
+		BulkParams: params,
+	})
+}
+
+// WrapInTx runs the content of the function in a single transaction.
+//
+// Only the outermost call of nested calls is counted as a transaction.
+func (a *Adapter) WrapInTx(ctx context.Context, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if ctx.Value(txKey) != nil {
+		return fn(ctx)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	a.begins++
+	a.mu.Unlock()
+
+	res, err := fn(context.WithValue(ctx, txKey, true))
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err != nil {
+		a.rollbacks++
+		return nil, err
+	}
+
+	if a.CommitErr != nil {
+		a.rollbacks++
+		return nil, &db.TxError{TxErr: a.CommitErr}
+	}
+
+	a.commits++
+
+	return res, nil
+}
+
+// WrapInTxWithOptions runs the content of the function in a single transaction.
+//
+// The options are ignored by the fake adapter.
+func (a *Adapter) WrapInTxWithOptions(ctx context.Context, opts *db.TxOptions, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	return a.WrapInTx(ctx, fn)
+}
+
+// WrapInTxWithRetry runs the content of the function in a single transaction,
+// running it again when it fails with an error wrapping ErrRetryable.
+//
+// The options are ignored by the fake adapter.
+func (a *Adapter) WrapInTxWithRetry(ctx context.Context, opts *db.TxOptions, policy db.RetryPolicy, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if ctx.Value(txKey) != nil {
+		return a.WrapInTx(ctx, fn)
+	}
+
+	retryable := func(err error) bool {
+		return errors.Is(err, ErrRetryable)
+	}
+
+	return internal.Retry(ctx, internal.RetryPolicy(policy), retryable, func(ctx context.Context) (interface{}, error) {
+		return a.WrapInTx(ctx, fn)
+	})
+}
+
+// Destruct will close the fake adapter.
+func (a *Adapter) Destruct() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.destructed = true
+
+	return nil
+}
+
+// run records the call and returns the result of the matching expectation.
+//
+// Same as the real adapters, a call made using a cancelled context results in the error of the context.
+func (a *Adapter) run(ctx context.Context, c Call) ([]map[string]interface{}, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c.InTx = ctx.Value(txKey) != nil
+	a.calls = append(a.calls, c)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := checkParameters(c); err != nil {
+		return nil, err
+	}
+
+	for _, e := range a.expectations {
+		if e.query == c.Query {
+			return e.result, e.err
+		}
+	}
+
+	return nil, fmt.Errorf("dbtest: unexpected query '%s'", c.Query)
+}
+
+// checkParameters checks whether all named parameters in the query are available in the params of the call.
+//
+// The SQLite dialect is used to find named parameters since it recognizes the most common quoting styles.
+func checkParameters(c Call) error {
+	params := c.BulkParams
+	if c.Method == MethodQuery || c.Method == MethodQueryEach {
+		params = []map[string]interface{}{c.Params}
+	}
+
+	for _, t := range internal.Tokenize(c.Query, internal.SQLite) {
+		if t.Type != internal.TokenParam {
+			continue
+		}
+
+		name := t.Name()
+
+		for _, pms := range params {
+			if _, ok := pms[name]; !ok {
+				return fmt.Errorf("dbtest: parameter '%s' is missing", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// normalize trims the query and collapses all whitespace to single spaces.
+func normalize(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// make sure the fake adapter satisfies the adapter interfaces
+var (
+	_ db.AdapterInterface     = (*Adapter)(nil)
+	_ db.BatchInsertInterface = (*Adapter)(nil)
+)
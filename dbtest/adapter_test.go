@@ -0,0 +1,342 @@
+package dbtest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kosatnkn/db"
+	"github.com/kosatnkn/db/dbtest"
+	"github.com/kosatnkn/db/internal"
+)
+
+// TestQuery tests returning of registered results.
+func TestQuery(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	adapter.Expect(`select * from sample where id = ?id`).WillReturn([]map[string]interface{}{
+		{"id": int64(1), "name": "Name 1"},
+	})
+
+	q := `select *
+		from sample
+		where id = ?id`
+	params := map[string]interface{}{"id": 1}
+
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("Need 1 record, got %d records", len(r))
+	}
+
+	need := "Name 1"
+	got := r[0]["name"]
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	calls := adapter.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("Need 1 call, got %d calls", len(calls))
+	}
+
+	cNeed := "select * from sample where id = ?id"
+	cGot := calls[0].Query
+	if cGot != cNeed {
+		t.Errorf("Need `%s`, got `%s`", cNeed, cGot)
+	}
+	if calls[0].Params["id"] != 1 {
+		t.Errorf("Need `1`, got `%v`", calls[0].Params["id"])
+	}
+}
+
+// TestQueryEach tests iterating over registered results.
+func TestQueryEach(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	q := `select * from sample`
+	adapter.Expect(q).WillReturn([]map[string]interface{}{
+		{"id": int64(1)},
+		{"id": int64(2)},
+		{"id": int64(3)},
+	})
+
+	errStop := errors.New("stop")
+	var ids []int64
+
+	err := adapter.QueryEach(context.Background(), q, nil, func(row map[string]interface{}) error {
+		ids = append(ids, row["id"].(int64))
+		if len(ids) == 2 {
+			return errStop
+		}
+
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("Need `%v`, got `%v`", errStop, err)
+	}
+
+	need := "[1 2]"
+	got := fmt.Sprintf("%v", ids)
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	calls := adapter.Calls()
+	if len(calls) != 1 || calls[0].Method != dbtest.MethodQueryEach {
+		t.Errorf("Need 1 %s call, got %v", dbtest.MethodQueryEach, calls)
+	}
+}
+
+// TestQueryFail tests returning of registered errors.
+func TestQueryFail(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	need := errors.New("query failed")
+	adapter.Expect(`delete from sample`).WillFail(need)
+
+	_, got := adapter.Query(context.Background(), `delete from sample`, nil)
+	if got != need {
+		t.Errorf("Need `%v`, got `%v`", need, got)
+	}
+}
+
+// TestQueryUnexpected tests running of a query that is not registered.
+func TestQueryUnexpected(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	_, err := adapter.Query(context.Background(), `select * from sample`, nil)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := "dbtest: unexpected query 'select * from sample'"
+	got := err.Error()
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestQueryMissingParameter tests running of a query without all named parameters.
+func TestQueryMissingParameter(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+	adapter.Expect(q)
+
+	params := []map[string]interface{}{
+		{"name": "Name 1", "password": "pwd1"},
+		{"name": "Name 2"},
+	}
+
+	_, err := adapter.QueryBulk(context.Background(), q, params)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := "dbtest: parameter 'password' is missing"
+	got := err.Error()
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestInsertBatch tests recording of batch inserts.
+func TestInsertBatch(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+	adapter.Expect(q).WillReturn([]map[string]interface{}{
+		{internal.AffectedRows: int64(2)},
+	})
+
+	var batch db.BatchInsertInterface = adapter
+
+	params := []map[string]interface{}{
+		{"name": "Name 1", "password": "pwd1"},
+		{"name": "Name 2", "password": "pwd2"},
+	}
+
+	_, err := batch.InsertBatch(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	calls := adapter.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("Need 1 call, got %d calls", len(calls))
+	}
+	if calls[0].Method != dbtest.MethodInsertBatch {
+		t.Errorf("Need `%s`, got `%s`", dbtest.MethodInsertBatch, calls[0].Method)
+	}
+	if len(calls[0].BulkParams) != 2 {
+		t.Errorf("Need 2 sets of parameters, got %d", len(calls[0].BulkParams))
+	}
+
+	// a query that cannot be batched
+	_, err = batch.InsertBatch(context.Background(), `update sample set name = ?name`, params)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := "dbtest: batch queries should be inserts"
+	got := err.Error()
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestWrapInTx tests tracking of transactions.
+func TestWrapInTx(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+	adapter.Expect(q).WillReturn([]map[string]interface{}{
+		{internal.AffectedRows: int64(1), internal.LastInsertID: int64(1)},
+	})
+
+	params := map[string]interface{}{"name": "Name 1", "password": "pwd1"}
+
+	// successful nested transaction
+	_, err := adapter.WrapInTx(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+			return adapter.Query(ctx, q, params)
+		})
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// failing transaction
+	_, err = adapter.WrapInTx(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return adapter.Query(ctx, `delete from sample`, nil)
+	})
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	// query outside of a transaction
+	_, err = adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "2, 1, 1"
+	got := fmt.Sprintf("%d, %d, %d", adapter.Begins(), adapter.Commits(), adapter.Rollbacks())
+	if got != need {
+		t.Errorf("Begins, commits, rollbacks: need `%s`, got `%s`", need, got)
+	}
+
+	calls := adapter.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("Need 3 calls, got %d calls", len(calls))
+	}
+
+	need = "true, true, false"
+	got = fmt.Sprintf("%t, %t, %t", calls[0].InTx, calls[1].InTx, calls[2].InTx)
+	if got != need {
+		t.Errorf("In tx: need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestQueryCancel tests running of a query using a cancelled context.
+func TestQueryCancel(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	adapter.Expect(`select * from sample`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := adapter.Query(ctx, `select * from sample`, nil)
+	if err != context.Canceled {
+		t.Errorf("Need `%v`, got `%v`", context.Canceled, err)
+	}
+}
+
+// TestWrapInTxCommitFail tests failing of committing transactions.
+func TestWrapInTxCommitFail(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+	adapter.CommitErr = errors.New("commit failed")
+
+	_, err := adapter.WrapInTx(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	})
+
+	var txErr *db.TxError
+	if !errors.As(err, &txErr) {
+		t.Fatalf("Need a transaction error, got %v", err)
+	}
+	if txErr.TxErr != adapter.CommitErr {
+		t.Errorf("Need `%v`, got `%v`", adapter.CommitErr, txErr.TxErr)
+	}
+
+	need := "0, 1"
+	got := fmt.Sprintf("%d, %d", adapter.Commits(), adapter.Rollbacks())
+	if got != need {
+		t.Errorf("Commits, rollbacks: need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestWrapInTxWithRetry tests retrying of transactions failing with a retryable error.
+func TestWrapInTxWithRetry(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	adapter.Expect(`update sample set name = 'Name 1'`).WillFail(dbtest.ErrRetryable)
+	adapter.Expect(`update sample set name = 'Name 2'`)
+
+	policy := db.RetryPolicy{MaxAttempts: 3}
+
+	var attempts []int
+
+	_, err := adapter.WrapInTxWithRetry(context.Background(), nil, policy, func(ctx context.Context) (interface{}, error) {
+		attempts = append(attempts, db.TxAttempt(ctx))
+
+		// fail only the first attempt
+		if db.TxAttempt(ctx) == 1 {
+			return adapter.Query(ctx, `update sample set name = 'Name 1'`, nil)
+		}
+
+		return adapter.Query(ctx, `update sample set name = 'Name 2'`, nil)
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "[1 2]"
+	got := fmt.Sprintf("%v", attempts)
+	if got != need {
+		t.Errorf("Attempts: need `%s`, got `%s`", need, got)
+	}
+
+	need = "2, 1, 1"
+	got = fmt.Sprintf("%d, %d, %d", adapter.Begins(), adapter.Commits(), adapter.Rollbacks())
+	if got != need {
+		t.Errorf("Begins, commits, rollbacks: need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestWrapInTxWithRetryExhausted tests giving up on a transaction after all attempts fail.
+func TestWrapInTxWithRetryExhausted(t *testing.T) {
+	adapter := dbtest.NewAdapter()
+
+	adapter.Expect(`update sample set name = 'Name 1'`).WillFail(dbtest.ErrRetryable)
+
+	policy := db.RetryPolicy{MaxAttempts: 3}
+
+	_, err := adapter.WrapInTxWithRetry(context.Background(), nil, policy, func(ctx context.Context) (interface{}, error) {
+		return adapter.Query(ctx, `update sample set name = 'Name 1'`, nil)
+	})
+	if err != dbtest.ErrRetryable {
+		t.Errorf("Need `%v`, got `%v`", dbtest.ErrRetryable, err)
+	}
+
+	need := 3
+	got := adapter.Begins()
+	if got != need {
+		t.Errorf("Begins: need `%d`, got `%d`", need, got)
+	}
+}
@@ -5,4 +5,6 @@ go 1.18
 require (
 	github.com/go-sql-driver/mysql v1.7.0
 	github.com/lib/pq v1.10.7
+	github.com/mattn/go-sqlite3 v1.14.16
+	gopkg.in/yaml.v3 v3.0.1
 )
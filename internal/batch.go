@@ -0,0 +1,224 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BatchQuery is a single row insert query split around its row of values,
+// so that the row can be repeated to insert many rows using a single statement.
+type BatchQuery struct {
+	dialect Dialect
+	head    string
+	row     string
+	tail    string
+	names   []string
+}
+
+// ParseBatchQuery splits a single row insert query like `insert into tbl (a, b) values (?a, ?b)`
+// around its row of values.
+//
+// Named parameters are only allowed in the row of values, since the parts before and after it
+// are shared by all rows inserted by a statement.
+func ParseBatchQuery(query string, d Dialect) (*BatchQuery, error) {
+	st := Classify(query, d)
+	if st.Command != "insert" && st.Command != "replace" {
+		return nil, errors.New("batch queries should be inserts")
+	}
+
+	tokens := Tokenize(strings.TrimSpace(query), d)
+
+	// find the VALUES keyword
+	depth, start := 0, -1
+	for i, t := range tokens {
+		if t.Type == TokenOther && t.Value == "(" {
+			depth++
+		}
+		if t.Type == TokenOther && t.Value == ")" {
+			depth--
+		}
+
+		if depth == 0 && t.Type == TokenWord && (strings.EqualFold(t.Value, "values") || strings.EqualFold(t.Value, "value")) {
+			start = skipSpace(tokens, i+1)
+			break
+		}
+	}
+	if start == -1 || start == len(tokens) || tokens[start].Value != "(" {
+		return nil, errors.New("batch queries should have a row of values")
+	}
+
+	// find the end of the row of values
+	end := start
+	for depth = 0; end < len(tokens); end++ {
+		if tokens[end].Type == TokenOther && tokens[end].Value == "(" {
+			depth++
+		}
+		if tokens[end].Type == TokenOther && tokens[end].Value == ")" {
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	if end == len(tokens) {
+		return nil, errors.New("batch queries should have a row of values")
+	}
+
+	next := skipSpace(tokens, end+1)
+	if next < len(tokens) && tokens[next].Value == "," {
+		return nil, errors.New("batch queries should have a single row of values")
+	}
+
+	for i, t := range tokens {
+		if t.Type == TokenParam && (i < start || i > end) {
+			return nil, fmt.Errorf("parameter '%s' is outside of the row of values", t.Name())
+		}
+	}
+
+	b := &BatchQuery{
+		dialect: d,
+		head:    join(tokens[:start]),
+		row:     join(tokens[start : end+1]),
+		tail:    join(tokens[end+1:]),
+	}
+
+	for _, t := range tokens[start : end+1] {
+		if t.Type == TokenParam {
+			b.names = append(b.names, t.Name())
+		}
+	}
+
+	return b, nil
+}
+
+// Split splits rows in to chunks where the rows of each chunk can be inserted using a single statement
+// of at most maxParams parameters and about maxBytes bytes.
+//
+// A limit of 0 means there is no limit. A row that exceeds the limits by itself makes up a chunk of its own.
+func (b *BatchQuery) Split(rows []map[string]interface{}, maxParams, maxBytes int) [][]map[string]interface{} {
+	var chunks [][]map[string]interface{}
+
+	base := len(b.head) + len(b.tail)
+	first, params, bytes := 0, 0, base
+
+	for i, row := range rows {
+		p, n := b.size(row)
+
+		if i > first && ((maxParams > 0 && params+p > maxParams) || (maxBytes > 0 && bytes+n > maxBytes)) {
+			chunks = append(chunks, rows[first:i])
+			first, params, bytes = i, 0, base
+		}
+
+		params += p
+		bytes += n
+	}
+
+	if first < len(rows) {
+		chunks = append(chunks, rows[first:])
+	}
+
+	return chunks
+}
+
+// Build creates a query inserting all rows along with its parameters.
+//
+// The named parameters of each row are renamed to be unique within the query,
+// so the query can be converted the same way as any other named parameter query.
+func (b *BatchQuery) Build(rows []map[string]interface{}) (string, map[string]interface{}, error) {
+	var q strings.Builder
+	params := make(map[string]interface{}, len(rows)*len(b.names))
+
+	q.WriteString(b.head)
+
+	for i, row := range rows {
+		for _, name := range b.names {
+			value, ok := row[name]
+			if !ok {
+				return "", nil, fmt.Errorf("parameter '%s' is missing", name)
+			}
+
+			params[b.rename(i, name)] = value
+		}
+
+		if i > 0 {
+			q.WriteString(", ")
+		}
+
+		q.WriteString(ReplaceParams(b.row, b.dialect, func(name string) string {
+			return string(ParamPrefix) + b.rename(i, name)
+		}))
+	}
+
+	q.WriteString(b.tail)
+
+	return q.String(), params, nil
+}
+
+// rename returns the unique name of the named parameter of the i'th row.
+//
+// The row number ends at the first underscore, so that different rows never result in the same name.
+func (b *BatchQuery) rename(i int, name string) string {
+	return "r" + strconv.Itoa(i) + "_" + name
+}
+
+// size returns the number of parameters and the approximate number of bytes needed to insert the row.
+func (b *BatchQuery) size(row map[string]interface{}) (int, int) {
+	// each row adds a separator as well
+	params, bytes := 0, len(b.row)+2
+
+	for _, name := range b.names {
+		if elems, ok := ExpandSlice(row[name]); ok {
+			params += len(elems)
+			for _, e := range elems {
+				bytes += valueSize(e)
+			}
+			continue
+		}
+
+		params++
+		bytes += valueSize(row[name])
+	}
+
+	return params, bytes
+}
+
+// valueSize returns the approximate number of bytes needed to send the value
+// including the placeholder and the overhead of the protocol.
+func valueSize(v interface{}) int {
+	const overhead = 16
+
+	switch v := v.(type) {
+	case nil:
+		return overhead
+	case string:
+		return overhead + len(v)
+	case []byte:
+		return overhead + len(v)
+	case time.Time:
+		return overhead + 32
+	default:
+		return overhead + 8
+	}
+}
+
+// skipSpace returns the position of the first token from i that is not a space or a comment.
+func skipSpace(tokens []Token, i int) int {
+	for i < len(tokens) && (tokens[i].Type == TokenSpace || tokens[i].Type == TokenComment) {
+		i++
+	}
+
+	return i
+}
+
+// join concatenates the values of tokens.
+func join(tokens []Token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString(t.Value)
+	}
+
+	return b.String()
+}
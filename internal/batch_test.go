@@ -0,0 +1,138 @@
+package internal_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+// TestBatchQueryBuild tests creating multi row inserts.
+func TestBatchQueryBuild(t *testing.T) {
+	q := "insert into tbl (a, b) values (?a, ?b) on duplicate key update b = values(b)"
+
+	bq, err := internal.ParseBatchQuery(q, internal.MySQL)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	rows := []map[string]interface{}{
+		{"a": 1, "b": "x"},
+		{"a": 2, "b": "y"},
+	}
+
+	got, params, err := bq.Build(rows)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "insert into tbl (a, b) values (?r0_a, ?r0_b), (?r1_a, ?r1_b) on duplicate key update b = values(b)"
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	pNeed := "map[r0_a:1 r0_b:x r1_a:2 r1_b:y]"
+	pGot := fmt.Sprintf("%v", params)
+	if pGot != pNeed {
+		t.Errorf("Need `%s`, got `%s`", pNeed, pGot)
+	}
+
+	// missing parameter
+	_, _, err = bq.Build([]map[string]interface{}{{"a": 1}})
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	eNeed := "parameter 'b' is missing"
+	if err.Error() != eNeed {
+		t.Errorf("Need `%s`, got `%s`", eNeed, err.Error())
+	}
+}
+
+// TestParseBatchQueryErrors tests rejecting queries that cannot be batched.
+func TestParseBatchQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		need  string
+	}{
+		{
+			query: "update tbl set a = ?a",
+			need:  "batch queries should be inserts",
+		},
+		{
+			query: "insert into tbl (a) select a from other where b = ?b",
+			need:  "batch queries should have a row of values",
+		},
+		{
+			query: "insert into tbl (a) values (?a), (?b)",
+			need:  "batch queries should have a single row of values",
+		},
+		{
+			query: "insert into tbl (a) values (?a) returning ?b",
+			need:  "parameter 'b' is outside of the row of values",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := internal.ParseBatchQuery(test.query, internal.Postgres)
+		if err == nil {
+			t.Errorf("%s: need error, got nil", test.query)
+			continue
+		}
+
+		if err.Error() != test.need {
+			t.Errorf("%s: need `%s`, got `%s`", test.query, test.need, err.Error())
+		}
+	}
+}
+
+// TestBatchQuerySplit tests splitting rows in to chunks within the limits.
+func TestBatchQuerySplit(t *testing.T) {
+	bq, err := internal.ParseBatchQuery("insert into tbl (a, b) values (?a, ?b) returning id", internal.Postgres)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	rows := make([]map[string]interface{}, 10)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"a": i, "b": strings.Repeat("x", 100)}
+	}
+
+	sizes := func(chunks [][]map[string]interface{}) string {
+		var s []string
+		for _, c := range chunks {
+			s = append(s, fmt.Sprint(len(c)))
+		}
+
+		return strings.Join(s, ",")
+	}
+
+	// parameter limit
+	need := "3,3,3,1"
+	got := sizes(bq.Split(rows, 6, 0))
+	if got != need {
+		t.Errorf("Parameter limit: need `%s`, got `%s`", need, got)
+	}
+
+	// byte limit
+	need = "2,2,2,2,2"
+	got = sizes(bq.Split(rows, 0, 400))
+	if got != need {
+		t.Errorf("Byte limit: need `%s`, got `%s`", need, got)
+	}
+
+	// a row larger than the limit
+	need = "1,1,1,1,1,1,1,1,1,1"
+	got = sizes(bq.Split(rows, 1, 0))
+	if got != need {
+		t.Errorf("Row over the limit: need `%s`, got `%s`", need, got)
+	}
+
+	// no limits
+	need = "10"
+	got = sizes(bq.Split(rows, 0, 0))
+	if got != need {
+		t.Errorf("No limits: need `%s`, got `%s`", need, got)
+	}
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables overlaid on configurations read from files.
+const EnvPrefix = "DB_"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// LoadYAML reads the YAML file at path in to the struct pointed to by v.
+//
+// Keys that do not belong to a field of v are rejected, so that misspelled settings do not go unnoticed.
+func LoadYAML(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("cannot read '%s': %v", path, err)
+	}
+
+	return nil
+}
+
+// ReadEnv sets the fields of the struct pointed to by v from environment variables.
+//
+// Each variable is named after the yaml tag of its field in upper case with prefix, like DB_POOL_SIZE
+// for `yaml:"pool_size"`. Fields of nested structs add the tag of the struct to the prefix, like DB_TLS_MODE.
+// Maps are set using URL query format like `a=1&b=2`, adding to the entries that are already there.
+//
+// The value can also be read from the file at the path in a variable with a _FILE suffix like DB_PASSWORD_FILE,
+// which is how Docker and Kubernetes make secrets available. Fields without a variable are left as they are.
+func ReadEnv(prefix string, v interface{}) error {
+	return readEnv(prefix, reflect.ValueOf(v).Elem())
+}
+
+// readEnv sets the fields of the struct v from environment variables with prefix.
+func readEnv(prefix string, v reflect.Value) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" || !f.IsExported() {
+			continue
+		}
+
+		name := prefix + strings.ToUpper(tag)
+
+		if f.Type.Kind() == reflect.Struct {
+			if err := readEnv(name+"_", v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok, err := lookupEnv(name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if err := setField(v.Field(i), value); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// lookupEnv returns the value of the environment variable name,
+// or the contents of the file at the path in the variable name_FILE.
+func lookupEnv(name string) (string, bool, error) {
+	value, ok := os.LookupEnv(name)
+
+	path, fromFile := os.LookupEnv(name + "_FILE")
+	if !fromFile {
+		return value, ok, nil
+	}
+
+	if ok {
+		return "", false, fmt.Errorf("only one of %s and %s_FILE should be set", name, name)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %v", name, err)
+	}
+
+	// files usually end with a new line that is not a part of the value
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// setField sets the field f from the string value of an environment variable.
+func setField(f reflect.Value, value string) error {
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid duration", value)
+		}
+
+		f.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(value)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, f.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid %s", value, f.Type())
+		}
+
+		f.SetInt(n)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid bool", value)
+		}
+
+		f.SetBool(b)
+
+	case reflect.Map:
+		q, err := url.ParseQuery(value)
+		if err != nil {
+			return fmt.Errorf("'%s' is not in the format a=1&b=2", value)
+		}
+
+		if f.IsNil() {
+			f.Set(reflect.MakeMap(f.Type()))
+		}
+		for k := range q {
+			f.SetMapIndex(reflect.ValueOf(k), reflect.ValueOf(q.Get(k)))
+		}
+
+	default:
+		return fmt.Errorf("cannot set a %s from the environment", f.Type())
+	}
+
+	return nil
+}
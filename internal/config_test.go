@@ -0,0 +1,110 @@
+package internal_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+type envTLS struct {
+	Mode string `yaml:"mode"`
+}
+
+type envConfig struct {
+	Host     string            `yaml:"host"`
+	Port     int               `yaml:"port"`
+	Password string            `yaml:"password"`
+	Check    bool              `yaml:"check"`
+	Timeout  time.Duration     `yaml:"timeout"`
+	TLS      envTLS            `yaml:"tls"`
+	Params   map[string]string `yaml:"params"`
+	Ignored  string
+}
+
+// TestReadEnv tests overlaying a configuration with environment variables.
+func TestReadEnv(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "password")
+	os.WriteFile(secret, []byte("s3cret\n"), 0600)
+
+	t.Setenv("TEST_PORT", "3307")
+	t.Setenv("TEST_PASSWORD_FILE", secret)
+	t.Setenv("TEST_CHECK", "true")
+	t.Setenv("TEST_TIMEOUT", "5s")
+	t.Setenv("TEST_TLS_MODE", "require")
+	t.Setenv("TEST_PARAMS", "loc=Local&search_path=a%2Cb")
+	t.Setenv("TEST_IGNORED", "x")
+
+	cfg := envConfig{
+		Host:   "localhost",
+		Port:   3306,
+		Params: map[string]string{"charset": "utf8mb4"},
+	}
+
+	if err := internal.ReadEnv("TEST_", &cfg); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "{localhost 3307 s3cret true 5s {require} map[charset:utf8mb4 loc:Local search_path:a,b] }"
+	got := fmt.Sprintf("%v", cfg)
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestReadEnvErrors tests rejecting invalid environment variables.
+func TestReadEnvErrors(t *testing.T) {
+	tests := []struct {
+		env  map[string]string
+		need string
+	}{
+		{
+			env:  map[string]string{"TEST_PORT": "abc"},
+			need: "TEST_PORT: 'abc' is not a valid int",
+		},
+		{
+			env:  map[string]string{"TEST_TIMEOUT": "10"},
+			need: "TEST_TIMEOUT: '10' is not a valid duration",
+		},
+		{
+			env:  map[string]string{"TEST_PASSWORD": "a", "TEST_PASSWORD_FILE": "b"},
+			need: "only one of TEST_PASSWORD and TEST_PASSWORD_FILE should be set",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.need, func(t *testing.T) {
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
+
+			err := internal.ReadEnv("TEST_", &envConfig{})
+			if err == nil {
+				t.Fatalf("Need error, got nil")
+			}
+
+			if err.Error() != test.need {
+				t.Errorf("Need `%s`, got `%s`", test.need, err.Error())
+			}
+		})
+	}
+}
+
+// TestLoadYAML tests rejecting unknown keys.
+func TestLoadYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("host: localhost\npasswrd: secret\n"), 0600)
+
+	err := internal.LoadYAML(path, &envConfig{})
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := fmt.Sprintf("cannot read '%s': yaml: unmarshal errors:\n  line 2: field passwrd not found in type internal_test.envConfig", path)
+	if err.Error() != need {
+		t.Errorf("Need `%s`, got `%s`", need, err.Error())
+	}
+}
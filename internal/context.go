@@ -5,3 +5,6 @@ type key string
 
 // TxKey is the key used to bind a transaction to context.
 const TxKey key = "tx"
+
+// AttemptKey is the key used to bind the attempt number of a retried transaction to context.
+const AttemptKey key = "attempt"
@@ -1,6 +1,7 @@
 package internal
 
 const (
-	AffectedRows string = "affected_rows"
-	LastInsertID string = "last_insert_id"
+	AffectedRows  string = "affected_rows"
+	LastInsertID  string = "last_insert_id"
+	LastInsertIDs string = "last_insert_ids"
 )
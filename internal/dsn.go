@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+)
+
+// KeyValueDSN creates a libpq style connection string of space separated key=value pairs.
+//
+// Values that are empty or contain spaces, quotes or backslashes are quoted,
+// so that they reach the driver as they are.
+func KeyValueDSN(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + quoteDSNValue(params[k])
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+// quoteDSNValue quotes v when it cannot be written as it is in a key=value pair.
+func quoteDSNValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " \t\n\r\f\v'\\") {
+		return v
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+	return "'" + r.Replace(v) + "'"
+}
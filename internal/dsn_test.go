@@ -0,0 +1,24 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+// TestKeyValueDSN tests quoting the values of a libpq style connection string.
+func TestKeyValueDSN(t *testing.T) {
+	params := map[string]string{
+		"user":             "postgres",
+		"password":         `p@ss/w 'o\rd`,
+		"dbname":           "test",
+		"application_name": "",
+		"search_path":      "app,public",
+	}
+
+	need := `application_name='' dbname=test password='p@ss/w \'o\\rd' search_path=app,public user=postgres`
+	got := internal.KeyValueDSN(params)
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
@@ -0,0 +1,230 @@
+package internal
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TagName is the name of the struct tag holding the column name of a field.
+const TagName = "db"
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// fieldCache keeps the fields of struct types so that they are found only once for each type.
+var fieldCache sync.Map
+
+// Fields returns the index paths of the fields of the struct type t by column name.
+//
+// A field is mapped to the column named by its `db:"name"` tag. Fields without a tag or with the tag `db:"-"`
+// are left out, except for embedded structs whose fields are included as if they belong to t.
+// When more than one field maps to the same column, the least nested one wins.
+func Fields(t reflect.Type) map[string][]int {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.(map[string][]int)
+	}
+
+	fields := make(map[string][]int)
+	collectFields(t, nil, fields)
+
+	fieldCache.Store(t, fields)
+
+	return fields
+}
+
+// collectFields adds the fields of the struct type t to fields, prefixing their index paths with index.
+func collectFields(t reflect.Type, index []int, fields map[string][]int) {
+	var embedded []reflect.StructField
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, _, _ := strings.Cut(f.Tag.Get(TagName), ",")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			// a pointer to an unexported struct cannot be allocated, so its fields cannot be set
+			if f.Anonymous && IsStruct(f.Type) && (f.IsExported() || f.Type.Kind() != reflect.Pointer) {
+				embedded = append(embedded, f)
+			}
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if _, ok := fields[name]; !ok {
+			fields[name] = append(append([]int{}, index...), i)
+		}
+	}
+
+	// fields of embedded structs are nested one level deeper, so they come after the fields of t
+	for _, f := range embedded {
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		collectFields(ft, append(append([]int{}, index...), f.Index...), fields)
+	}
+}
+
+// IsStruct tells whether t is a struct or a pointer to a struct that should be mapped field by field
+// instead of as a single value like time.Time or a sql.Scanner.
+func IsStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+
+	return !reflect.PointerTo(t).Implements(scannerType)
+}
+
+// FieldByIndex returns the field of the struct v at the index path, allocating nil embedded struct pointers on the way.
+func FieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+	}
+
+	return v
+}
+
+// LookupField returns the field of the struct v at the index path.
+//
+// The field is not found when a nil embedded struct pointer is on the way.
+func LookupField(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+
+		v = v.Field(x)
+	}
+
+	return v, true
+}
+
+// Assign sets the value read from the database to dest.
+//
+// The conversions are the same as the ones database/sql does when scanning. In addition, NULL sets dest to its zero value,
+// so a pointer field is needed to tell a NULL apart from a zero value.
+func Assign(dest reflect.Value, value interface{}) error {
+	if dest.CanAddr() && dest.Addr().Type().Implements(scannerType) {
+		return dest.Addr().Interface().(sql.Scanner).Scan(value)
+	}
+
+	if value == nil {
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+
+	if dest.Kind() == reflect.Pointer {
+		elem := reflect.New(dest.Type().Elem())
+		if err := Assign(elem.Elem(), value); err != nil {
+			return err
+		}
+
+		dest.Set(elem)
+
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(dest.Type()) {
+		dest.Set(v)
+		return nil
+	}
+
+	switch dest.Kind() {
+	case reflect.String:
+		var s sql.NullString
+		if err := s.Scan(value); err != nil {
+			return err
+		}
+
+		dest.SetString(s.String)
+
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n sql.NullInt64
+		if err := n.Scan(value); err != nil {
+			return err
+		}
+		if dest.OverflowInt(n.Int64) {
+			return fmt.Errorf("value %d overflows %s", n.Int64, dest.Type())
+		}
+
+		dest.SetInt(n.Int64)
+
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n sql.NullInt64
+		if err := n.Scan(value); err != nil {
+			return err
+		}
+		if n.Int64 < 0 || dest.OverflowUint(uint64(n.Int64)) {
+			return fmt.Errorf("value %d overflows %s", n.Int64, dest.Type())
+		}
+
+		dest.SetUint(uint64(n.Int64))
+
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		var n sql.NullFloat64
+		if err := n.Scan(value); err != nil {
+			return err
+		}
+
+		dest.SetFloat(n.Float64)
+
+		return nil
+
+	case reflect.Bool:
+		var b sql.NullBool
+		if err := b.Scan(value); err != nil {
+			return err
+		}
+
+		dest.SetBool(b.Bool)
+
+		return nil
+
+	case reflect.Slice:
+		if s, ok := value.(string); ok && dest.Type().Elem().Kind() == reflect.Uint8 {
+			dest.SetBytes([]byte(s))
+			return nil
+		}
+	}
+
+	if v.Type().ConvertibleTo(dest.Type()) && v.Kind() == dest.Kind() {
+		dest.Set(v.Convert(dest.Type()))
+		return nil
+	}
+
+	return fmt.Errorf("cannot assign %T to %s", value, dest.Type())
+}
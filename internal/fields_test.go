@@ -0,0 +1,48 @@
+package internal_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+type Inner struct {
+	ID   int    `db:"id"`
+	Note string `db:"note"`
+}
+
+type outer struct {
+	*Inner
+	ID       int    `db:"id"`
+	Name     string `db:"name,omitempty"`
+	Skipped  string `db:"-"`
+	Untagged string
+	hidden   string `db:"hidden"`
+}
+
+// TestFields tests finding the fields of a struct by column name.
+func TestFields(t *testing.T) {
+	fields := internal.Fields(reflect.TypeOf(outer{}))
+
+	need := "map[id:[1] name:[2] note:[0 1]]"
+	got := fmt.Sprintf("%v", fields)
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// nil embedded struct pointers are allocated when setting a field
+	var o outer
+	v := reflect.ValueOf(&o).Elem()
+
+	if err := internal.Assign(internal.FieldByIndex(v, fields["note"]), []byte("a note")); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need = "a note"
+	got = o.Inner.Note
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
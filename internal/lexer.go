@@ -0,0 +1,325 @@
+package internal
+
+import (
+	"strings"
+)
+
+// Dialect defines the quoting and commenting rules of a database.
+type Dialect int
+
+const (
+	// MySQL quotes strings with single or double quotes allowing backslash escapes
+	// and quotes identifiers using backticks.
+	MySQL Dialect = iota
+	// Postgres quotes strings with single quotes, escape strings with E'' and dollar quotes,
+	// and quotes identifiers using double quotes.
+	Postgres
+	// SQLite quotes strings with single quotes and identifiers using double quotes, backticks or square brackets.
+	SQLite
+)
+
+// TokenType is the type of a token.
+type TokenType int
+
+const (
+	// TokenSpace is a run of whitespace.
+	TokenSpace TokenType = iota
+	// TokenWord is a keyword, an unquoted identifier or a number.
+	TokenWord
+	// TokenString is a string literal including its quotes.
+	TokenString
+	// TokenIdentifier is a quoted identifier including its quotes.
+	TokenIdentifier
+	// TokenComment is a line or block comment.
+	TokenComment
+	// TokenParam is a named parameter including its prefix.
+	TokenParam
+	// TokenOther is a single character of punctuation or an operator.
+	TokenOther
+)
+
+// ParamPrefix is the prefix used to denote a named parameter in a query.
+const ParamPrefix = '?'
+
+// Token is a lexical unit of a query.
+type Token struct {
+	Type  TokenType
+	Value string
+}
+
+// Name returns the name of a named parameter token without the prefix.
+func (t Token) Name() string {
+	return t.Value[1:]
+}
+
+// Tokenize splits the query in to tokens using the rules of the dialect.
+//
+// Concatenating the values of all tokens results in the original query.
+// Unterminated strings, identifiers and comments extend to the end of the query.
+func Tokenize(query string, d Dialect) []Token {
+	l := lexer{query: query, dialect: d}
+
+	var tokens []Token
+	for l.pos < len(l.query) {
+		tokens = append(tokens, l.next())
+	}
+
+	return tokens
+}
+
+// ReplaceParams replaces each named parameter in the query with the output of fn.
+//
+// fn is called with the name of the parameter in the order that parameters are found in the query.
+// Named parameters inside strings, quoted identifiers and comments are left as they are.
+func ReplaceParams(query string, d Dialect, fn func(name string) string) string {
+	var b strings.Builder
+
+	for _, t := range Tokenize(query, d) {
+		if t.Type == TokenParam {
+			b.WriteString(fn(t.Name()))
+			continue
+		}
+
+		b.WriteString(t.Value)
+	}
+
+	return b.String()
+}
+
+// lexer holds the state of tokenizing a single query.
+type lexer struct {
+	query   string
+	dialect Dialect
+	pos     int
+}
+
+// next reads the token starting at the current position.
+func (l *lexer) next() Token {
+	start := l.pos
+	c := l.query[l.pos]
+
+	switch {
+	case isSpace(c):
+		l.skipWhile(isSpace)
+		return l.token(TokenSpace, start)
+
+	case c == '-' && l.peek(1) == '-' && l.isLineComment():
+		l.skipLine()
+		return l.token(TokenComment, start)
+
+	case c == '#' && l.dialect == MySQL:
+		l.skipLine()
+		return l.token(TokenComment, start)
+
+	case c == '/' && l.peek(1) == '*':
+		l.skipBlockComment()
+		return l.token(TokenComment, start)
+
+	case c == '\'':
+		l.skipQuoted('\'', l.dialect == MySQL)
+		return l.token(TokenString, start)
+
+	case c == '"' && l.dialect == MySQL:
+		l.skipQuoted('"', true)
+		return l.token(TokenString, start)
+
+	case c == '"':
+		l.skipQuoted('"', false)
+		return l.token(TokenIdentifier, start)
+
+	case c == '`' && l.dialect != Postgres:
+		l.skipQuoted('`', false)
+		return l.token(TokenIdentifier, start)
+
+	case c == '[' && l.dialect == SQLite:
+		l.skipUntil(']')
+		return l.token(TokenIdentifier, start)
+
+	case c == '$' && l.dialect == Postgres && l.skipDollarQuoted():
+		return l.token(TokenString, start)
+
+	case c == ParamPrefix && isParam(l.peek(1)):
+		l.pos++
+		l.skipWhile(isParam)
+		return l.token(TokenParam, start)
+
+	case isWord(c):
+		l.skipWhile(isIdentifier)
+
+		// escape string constants in postgres look like E'...'
+		if l.dialect == Postgres && l.pos-start == 1 && (c == 'E' || c == 'e') && l.peek(0) == '\'' {
+			l.skipQuoted('\'', true)
+			return l.token(TokenString, start)
+		}
+
+		return l.token(TokenWord, start)
+	}
+
+	l.pos++
+	return l.token(TokenOther, start)
+}
+
+// token creates a token of type t using the query from start to the current position.
+func (l *lexer) token(t TokenType, start int) Token {
+	return Token{Type: t, Value: l.query[start:l.pos]}
+}
+
+// peek returns the byte at offset n from the current position or 0 when it is out of range.
+func (l *lexer) peek(n int) byte {
+	if l.pos+n >= len(l.query) {
+		return 0
+	}
+
+	return l.query[l.pos+n]
+}
+
+// skipWhile advances the position while fn matches the current byte.
+func (l *lexer) skipWhile(fn func(byte) bool) {
+	for l.pos < len(l.query) && fn(l.query[l.pos]) {
+		l.pos++
+	}
+}
+
+// skipUntil advances the position past the next occurrence of c.
+func (l *lexer) skipUntil(c byte) {
+	i := strings.IndexByte(l.query[l.pos+1:], c)
+	if i < 0 {
+		l.pos = len(l.query)
+		return
+	}
+
+	l.pos += i + 2
+}
+
+// skipLine advances the position to the end of the current line.
+func (l *lexer) skipLine() {
+	i := strings.IndexByte(l.query[l.pos:], '\n')
+	if i < 0 {
+		l.pos = len(l.query)
+		return
+	}
+
+	l.pos += i
+}
+
+// isLineComment checks whether the "--" at the current position starts a comment.
+//
+// MySQL requires "--" to be followed by whitespace or a control character to be treated as a comment.
+func (l *lexer) isLineComment() bool {
+	if l.dialect != MySQL {
+		return true
+	}
+
+	c := l.peek(2)
+
+	return c == 0 || c <= ' '
+}
+
+// skipBlockComment advances the position past the end of the block comment starting at the current position.
+//
+// Postgres allows block comments to be nested.
+func (l *lexer) skipBlockComment() {
+	depth := 0
+
+	for l.pos < len(l.query) {
+		switch {
+		case l.peek(0) == '/' && l.peek(1) == '*':
+			if depth == 0 || l.dialect == Postgres {
+				depth++
+			}
+			l.pos += 2
+		case l.peek(0) == '*' && l.peek(1) == '/':
+			depth--
+			l.pos += 2
+			if depth == 0 {
+				return
+			}
+		default:
+			l.pos++
+		}
+	}
+}
+
+// skipQuoted advances the position past the end of the quoted string starting at the current position.
+//
+// A doubled quote character is always treated as an escaped quote.
+// When backslash is true a backslash escapes the following character.
+func (l *lexer) skipQuoted(quote byte, backslash bool) {
+	l.pos++
+
+	for l.pos < len(l.query) {
+		c := l.query[l.pos]
+
+		switch {
+		case backslash && c == '\\':
+			l.pos += 2
+		case c == quote && l.peek(1) == quote:
+			l.pos += 2
+		case c == quote:
+			l.pos++
+			return
+		default:
+			l.pos++
+		}
+	}
+
+	// an escape at the very end can move the position past the end
+	l.pos = len(l.query)
+}
+
+// skipDollarQuoted advances the position past the end of the dollar quoted string starting at the current position.
+//
+// Dollar quoted strings look like $$...$$ or $tag$...$tag$.
+// Returns false without advancing when the current position does not start a dollar quoted string,
+// as in the case of positional parameters like $1.
+func (l *lexer) skipDollarQuoted() bool {
+	end := l.pos + 1
+	if end < len(l.query) && !isDigit(l.query[end]) {
+		for end < len(l.query) && isWord(l.query[end]) {
+			end++
+		}
+	}
+
+	if end >= len(l.query) || l.query[end] != '$' {
+		return false
+	}
+
+	tag := l.query[l.pos : end+1]
+
+	i := strings.Index(l.query[end+1:], tag)
+	if i < 0 {
+		l.pos = len(l.query)
+		return true
+	}
+
+	l.pos = end + 1 + i + len(tag)
+
+	return true
+}
+
+// isSpace checks whether c is a whitespace character.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// isDigit checks whether c is a decimal digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isWord checks whether c can be a part of a word.
+//
+// Bytes of multi byte UTF-8 characters are treated as word characters.
+func isWord(c byte) bool {
+	return isParam(c) || c >= 0x80
+}
+
+// isParam checks whether c can be a part of a named parameter name.
+func isParam(c byte) bool {
+	return c == '_' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isIdentifier checks whether c can be a part of an unquoted identifier.
+func isIdentifier(c byte) bool {
+	return isWord(c) || c == '$'
+}
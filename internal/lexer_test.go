@@ -0,0 +1,199 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+// replace replaces all named parameters in the query with a '?' keeping a list of their names.
+func replace(query string, d internal.Dialect) (string, string) {
+	var names []string
+
+	q := internal.ReplaceParams(query, d, func(name string) string {
+		names = append(names, name)
+		return "?"
+	})
+
+	return q, strings.Join(names, ",")
+}
+
+// TestReplaceParams tests replacing of named parameters for all dialects.
+func TestReplaceParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect internal.Dialect
+		query   string
+		need    string
+		params  string
+	}{
+		{
+			name:    "mysql placeholders",
+			dialect: internal.MySQL,
+			query:   "update tbl set col1 = ?col1, col2 = ?col2 where col3 = ?col3",
+			need:    "update tbl set col1 = ?, col2 = ? where col3 = ?",
+			params:  "col1,col2,col3",
+		},
+		{
+			name:    "string literal",
+			dialect: internal.MySQL,
+			query:   "select * from tbl where status = '?draft' and id = ?id",
+			need:    "select * from tbl where status = '?draft' and id = ?",
+			params:  "id",
+		},
+		{
+			name:    "mysql backslash escaped quote",
+			dialect: internal.MySQL,
+			query:   `select * from tbl where col = 'it\'s ?not' and id = ?id`,
+			need:    `select * from tbl where col = 'it\'s ?not' and id = ?`,
+			params:  "id",
+		},
+		{
+			name:    "mysql double quoted string",
+			dialect: internal.MySQL,
+			query:   `select * from tbl where col = "?not" and id = ?id`,
+			need:    `select * from tbl where col = "?not" and id = ?`,
+			params:  "id",
+		},
+		{
+			name:    "mysql backtick identifier",
+			dialect: internal.MySQL,
+			query:   "select `?col` from tbl where id = ?id",
+			need:    "select `?col` from tbl where id = ?",
+			params:  "id",
+		},
+		{
+			name:    "mysql hash comment",
+			dialect: internal.MySQL,
+			query:   "select * from tbl # ?not\nwhere id = ?id",
+			need:    "select * from tbl # ?not\nwhere id = ?",
+			params:  "id",
+		},
+		{
+			name:    "mysql double dash without space is not a comment",
+			dialect: internal.MySQL,
+			query:   "select 1--?id",
+			need:    "select 1--?",
+			params:  "id",
+		},
+		{
+			name:    "line comment",
+			dialect: internal.Postgres,
+			query:   "select * from tbl -- ?not\nwhere id = ?id",
+			need:    "select * from tbl -- ?not\nwhere id = ?",
+			params:  "id",
+		},
+		{
+			name:    "block comment",
+			dialect: internal.MySQL,
+			query:   "select /* ?not */ * from tbl where id = ?id",
+			need:    "select /* ?not */ * from tbl where id = ?",
+			params:  "id",
+		},
+		{
+			name:    "postgres nested block comment",
+			dialect: internal.Postgres,
+			query:   "select /* a /* ?not */ ?not */ * from tbl where id = ?id",
+			need:    "select /* a /* ?not */ ?not */ * from tbl where id = ?",
+			params:  "id",
+		},
+		{
+			name:    "postgres doubled quote",
+			dialect: internal.Postgres,
+			query:   "select * from tbl where col = 'it''s ?not' and id = ?id",
+			need:    "select * from tbl where col = 'it''s ?not' and id = ?",
+			params:  "id",
+		},
+		{
+			name:    "postgres backslash does not escape in standard strings",
+			dialect: internal.Postgres,
+			query:   `select * from tbl where col = 'c:\' and id = ?id`,
+			need:    `select * from tbl where col = 'c:\' and id = ?`,
+			params:  "id",
+		},
+		{
+			name:    "postgres escape string",
+			dialect: internal.Postgres,
+			query:   `select * from tbl where col = E'it\'s ?not' and id = ?id`,
+			need:    `select * from tbl where col = E'it\'s ?not' and id = ?`,
+			params:  "id",
+		},
+		{
+			name:    "postgres quoted identifier",
+			dialect: internal.Postgres,
+			query:   `select "?col" from tbl where id = ?id`,
+			need:    `select "?col" from tbl where id = ?`,
+			params:  "id",
+		},
+		{
+			name:    "postgres dollar quoted string",
+			dialect: internal.Postgres,
+			query:   "select $$it's ?not$$, $tag$ ?not $$ $tag$ from tbl where id = ?id",
+			need:    "select $$it's ?not$$, $tag$ ?not $$ $tag$ from tbl where id = ?",
+			params:  "id",
+		},
+		{
+			name:    "postgres positional parameter",
+			dialect: internal.Postgres,
+			query:   "select * from tbl where a = $1 and id = ?id",
+			need:    "select * from tbl where a = $1 and id = ?",
+			params:  "id",
+		},
+		{
+			name:    "postgres jsonb operators",
+			dialect: internal.Postgres,
+			query:   "select * from tbl where data ?| array['a'] and data ?& array['b'] and data ? 'c' and id = ?id",
+			need:    "select * from tbl where data ?| array['a'] and data ?& array['b'] and data ? 'c' and id = ?",
+			params:  "id",
+		},
+		{
+			name:    "postgres type cast",
+			dialect: internal.Postgres,
+			query:   "select * from tbl where id = ?id::int",
+			need:    "select * from tbl where id = ?::int",
+			params:  "id",
+		},
+		{
+			name:    "sqlite bracket identifier",
+			dialect: internal.SQLite,
+			query:   "select [?col] from tbl where id = ?id",
+			need:    "select [?col] from tbl where id = ?",
+			params:  "id",
+		},
+		{
+			name:    "unterminated string",
+			dialect: internal.SQLite,
+			query:   "select * from tbl where id = ?id and col = '?not",
+			need:    "select * from tbl where id = ? and col = '?not",
+			params:  "id",
+		},
+	}
+
+	for _, test := range tests {
+		got, params := replace(test.query, test.dialect)
+		if got != test.need {
+			t.Errorf("%s: need `%s`, got `%s`", test.name, test.need, got)
+		}
+		if params != test.params {
+			t.Errorf("%s: need params `%s`, got `%s`", test.name, test.params, params)
+		}
+	}
+}
+
+// TestTokenize tests that tokens can be joined back to the original query.
+func TestTokenize(t *testing.T) {
+	query := "select `a`, \"b\", 'c''d', E'\\e', $$f$$, [g] -- h\n/* i */ from tbl where id = ?id"
+
+	for _, d := range []internal.Dialect{internal.MySQL, internal.Postgres, internal.SQLite} {
+		var b strings.Builder
+		for _, token := range internal.Tokenize(query, d) {
+			b.WriteString(token.Value)
+		}
+
+		got := b.String()
+		if got != query {
+			t.Errorf("Dialect %d: need `%s`, got `%s`", d, query, got)
+		}
+	}
+}
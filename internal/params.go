@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+)
+
+// ExpandSlice returns the elements of v when v is a slice or an array that should be expanded
+// in to a list of parameters, as in the case of an IN clause.
+//
+// []byte values and values implementing driver.Valuer (like pq.Array) are single values and are not expanded.
+func ExpandSlice(v interface{}) ([]interface{}, bool) {
+	if v == nil {
+		return nil, false
+	}
+
+	if _, ok := v.(driver.Valuer); ok {
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	if rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+
+	elems := make([]interface{}, rv.Len())
+	for i := range elems {
+		elems[i] = rv.Index(i).Interface()
+	}
+
+	return elems, true
+}
+
+// StmtCache keeps prepared statements by query so that each distinct query is prepared only once.
+//
+// This is used when running the same named parameter query with different sets of parameters,
+// where expanding slice parameters can change the converted query from one set to the next.
+type StmtCache struct {
+	prepare func(query string) (*sql.Stmt, error)
+	stmts   map[string]*sql.Stmt
+}
+
+// NewStmtCache creates a new statement cache that uses prepare to create statements.
+func NewStmtCache(prepare func(query string) (*sql.Stmt, error)) *StmtCache {
+	return &StmtCache{
+		prepare: prepare,
+		stmts:   make(map[string]*sql.Stmt),
+	}
+}
+
+// Get returns the prepared statement for the query preparing it when it is not already prepared.
+func (c *StmtCache) Get(query string) (*sql.Stmt, error) {
+	if stmt, ok := c.stmts[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := c.prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.stmts[query] = stmt
+
+	return stmt, nil
+}
+
+// Close closes all prepared statements.
+func (c *StmtCache) Close() {
+	for _, stmt := range c.stmts {
+		stmt.Close()
+	}
+}
@@ -0,0 +1,38 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+// TestExpandSlice tests deciding which values are expanded in to a list of parameters.
+func TestExpandSlice(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		need  string
+	}{
+		{[]int{1, 2, 3}, "[1 2 3] true"},
+		{[2]string{"a", "b"}, "[a b] true"},
+		{[]interface{}{1, "a"}, "[1 a] true"},
+		{[]int{}, "[] true"},
+		{1, "[] false"},
+		{"abc", "[] false"},
+		{nil, "[] false"},
+		{[]byte("abc"), "[] false"},
+		{pq.Array([]int{1, 2}), "[] false"},
+		{pq.StringArray{"a", "b"}, "[] false"},
+	}
+
+	for _, test := range tests {
+		elems, ok := internal.ExpandSlice(test.value)
+
+		got := fmt.Sprintf("%v %t", elems, ok)
+		if got != test.need {
+			t.Errorf("Value %#v: need `%s`, got `%s`", test.value, test.need, got)
+		}
+	}
+}
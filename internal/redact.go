@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"strings"
+)
+
+// Redacted replaces secrets in output that may end up in logs.
+const Redacted = "******"
+
+// RedactParams returns a copy of params with the values of password like parameters redacted.
+func RedactParams(params map[string]string) map[string]string {
+	if params == nil {
+		return nil
+	}
+
+	redacted := make(map[string]string, len(params))
+	for k, v := range params {
+		if strings.Contains(strings.ToLower(k), "password") {
+			v = Redacted
+		}
+
+		redacted[k] = v
+	}
+
+	return redacted
+}
+
+// redactedError is an error with a secret removed from its message.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// RedactError removes secret from the message of err, keeping err available to errors.Is and errors.As.
+func RedactError(err error, secret string) error {
+	if err == nil || secret == "" || !strings.Contains(err.Error(), secret) {
+		return err
+	}
+
+	return &redactedError{
+		msg: strings.ReplaceAll(err.Error(), secret, Redacted),
+		err: err,
+	}
+}
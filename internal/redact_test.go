@@ -0,0 +1,44 @@
+package internal_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+// TestRedactError tests removing a secret from an error message.
+func TestRedactError(t *testing.T) {
+	cause := errors.New("cannot parse `password=s3cret`")
+	err := internal.RedactError(fmt.Errorf("adapter: %w", cause), "s3cret")
+
+	need := "adapter: cannot parse `password=******`"
+	if err.Error() != need {
+		t.Errorf("Need `%s`, got `%s`", need, err.Error())
+	}
+
+	if !errors.Is(err, cause) {
+		t.Errorf("Need the redacted error to wrap the original one")
+	}
+
+	// nothing to redact
+	if got := internal.RedactError(cause, ""); got != cause {
+		t.Errorf("Need the error as it is, got `%v`", got)
+	}
+}
+
+// TestRedactParams tests redacting password like parameters.
+func TestRedactParams(t *testing.T) {
+	params := map[string]string{"charset": "utf8mb4", "sslpassword": "s3cret"}
+
+	need := "map[charset:utf8mb4 sslpassword:******]"
+	got := fmt.Sprintf("%v", internal.RedactParams(params))
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	if params["sslpassword"] != "s3cret" {
+		t.Errorf("Need the params to stay as they are, got `%s`", params["sslpassword"])
+	}
+}
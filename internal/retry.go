@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how many times and how often a failing transaction is retried.
+//
+// This has the same fields as db.RetryPolicy so that one can be converted to the other.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// Retry calls run until it succeeds, fails with an error that is not retryable or runs out of attempts.
+//
+// The attempt number, starting from 1, is bound to the context passed to run under AttemptKey.
+// Waiting between attempts stops when the context is cancelled.
+func Retry(ctx context.Context, policy RetryPolicy, retryable func(err error) bool, run func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := run(context.WithValue(ctx, AttemptKey, attempt))
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) {
+			return res, err
+		}
+
+		t := time.NewTimer(policy.backoff(attempt))
+
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// backoff returns the time to wait after the given attempt.
+//
+// The wait grows exponentially from BaseDelay up to MaxDelay, and a random wait up to that value is used (full jitter)
+// so that transactions that conflicted with each other do not retry at the same time.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < math.MaxInt64/2; i++ {
+		d *= 2
+	}
+
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
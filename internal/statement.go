@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"strings"
+)
+
+// readCommands are the commands that read data and return rows.
+var readCommands = map[string]bool{
+	"select":   true,
+	"values":   true,
+	"table":    true,
+	"show":     true,
+	"explain":  true,
+	"describe": true,
+	"desc":     true,
+	"pragma":   true,
+}
+
+// mainCommands are the commands that can follow the common table expressions of a WITH clause.
+var mainCommands = map[string]bool{
+	"select":  true,
+	"values":  true,
+	"table":   true,
+	"insert":  true,
+	"update":  true,
+	"delete":  true,
+	"replace": true,
+	"merge":   true,
+}
+
+// Statement describes the kind of a query.
+type Statement struct {
+	// Command is the main command of the statement in lower case.
+	//
+	// Leading comments and parentheses are skipped and for a statement starting with a WITH clause
+	// this is the command that follows the common table expressions.
+	Command string
+
+	// Returning tells whether the statement has a RETURNING clause.
+	Returning bool
+}
+
+// Classify finds the kind of the query using the rules of the dialect.
+func Classify(query string, d Dialect) Statement {
+	var s Statement
+
+	// depth is the parenthesis nesting level and base is the level at which the statement starts
+	depth, base := 0, -1
+	with := false
+
+	for _, t := range Tokenize(query, d) {
+		switch t.Type {
+		case TokenOther:
+			switch t.Value {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+			continue
+		case TokenWord:
+		default:
+			continue
+		}
+
+		word := strings.ToLower(t.Value)
+
+		if base == -1 {
+			base = depth
+		}
+
+		switch {
+		case s.Command == "" && !with && word == "with":
+			with = true
+		case s.Command == "" && !with:
+			s.Command = word
+		case s.Command == "" && depth == base && mainCommands[word]:
+			// common table expressions are enclosed in parentheses,
+			// so the first command outside of them is the main command
+			s.Command = word
+		case s.Command != "" && depth == base && word == "returning":
+			s.Returning = true
+		}
+	}
+
+	return s
+}
+
+// Reads tells whether the statement only reads data.
+func (s Statement) Reads() bool {
+	return readCommands[s.Command]
+}
+
+// ReturnsRows tells whether running the statement produces rows.
+func (s Statement) ReturnsRows() bool {
+	return s.Reads() || s.Returning
+}
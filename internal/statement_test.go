@@ -0,0 +1,171 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+// TestClassify tests finding the kind of a statement.
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		dialect   internal.Dialect
+		query     string
+		command   string
+		returning bool
+		rows      bool
+	}{
+		{
+			name:    "select",
+			dialect: internal.MySQL,
+			query:   "SELECT * FROM tbl",
+			command: "select",
+			rows:    true,
+		},
+		{
+			name:    "leading whitespace and comments",
+			dialect: internal.MySQL,
+			query:   "\n\t-- find rows\n# more\n/* and more */ select 1",
+			command: "select",
+			rows:    true,
+		},
+		{
+			name:    "leading parenthesis",
+			dialect: internal.Postgres,
+			query:   "(select 1) union (select 2)",
+			command: "select",
+			rows:    true,
+		},
+		{
+			name:    "with select",
+			dialect: internal.Postgres,
+			query:   "with t (a) as (select 1), u as (select 2) select * from t, u",
+			command: "select",
+			rows:    true,
+		},
+		{
+			name:    "with insert",
+			dialect: internal.MySQL,
+			query:   "with recursive t as (select 1 as a) insert into tbl select * from t",
+			command: "insert",
+		},
+		{
+			name:    "with modifying cte",
+			dialect: internal.Postgres,
+			query:   "with d as (delete from tbl returning *) select count(*) from d",
+			command: "select",
+			rows:    true,
+		},
+		{
+			name:    "show",
+			dialect: internal.MySQL,
+			query:   "SHOW TABLES",
+			command: "show",
+			rows:    true,
+		},
+		{
+			name:    "explain",
+			dialect: internal.Postgres,
+			query:   "explain update tbl set a = 1",
+			command: "explain",
+			rows:    true,
+		},
+		{
+			name:    "values",
+			dialect: internal.Postgres,
+			query:   "VALUES (1), (2)",
+			command: "values",
+			rows:    true,
+		},
+		{
+			name:    "table",
+			dialect: internal.Postgres,
+			query:   "table tbl",
+			command: "table",
+			rows:    true,
+		},
+		{
+			name:    "insert",
+			dialect: internal.MySQL,
+			query:   "insert into tbl (a) values (?a)",
+			command: "insert",
+		},
+		{
+			name:      "insert returning",
+			dialect:   internal.Postgres,
+			query:     "insert into tbl (a) values (?a) returning id",
+			command:   "insert",
+			returning: true,
+			rows:      true,
+		},
+		{
+			name:      "update returning",
+			dialect:   internal.Postgres,
+			query:     "UPDATE tbl SET a = 1 RETURNING *",
+			command:   "update",
+			returning: true,
+			rows:      true,
+		},
+		{
+			name:      "delete returning",
+			dialect:   internal.SQLite,
+			query:     "delete from tbl where id = ?id returning id",
+			command:   "delete",
+			returning: true,
+			rows:      true,
+		},
+		{
+			name:    "returning in a string",
+			dialect: internal.Postgres,
+			query:   "update tbl set a = 'returning'",
+			command: "update",
+		},
+		{
+			name:    "returning in a comment",
+			dialect: internal.Postgres,
+			query:   "delete from tbl -- returning *",
+			command: "delete",
+		},
+		{
+			name:    "returning in a subquery",
+			dialect: internal.Postgres,
+			query:   "update tbl set a = (select returning from other)",
+			command: "update",
+		},
+		{
+			name:    "empty",
+			dialect: internal.MySQL,
+			query:   "",
+			command: "",
+		},
+		{
+			name:    "short",
+			dialect: internal.MySQL,
+			query:   "sel",
+			command: "sel",
+		},
+		{
+			name:    "only a comment",
+			dialect: internal.Postgres,
+			query:   "/* nothing */",
+			command: "",
+		},
+	}
+
+	for _, test := range tests {
+		got := internal.Classify(test.query, test.dialect)
+
+		if got.Command != test.command {
+			t.Errorf("%s: need command `%s`, got `%s`", test.name, test.command, got.Command)
+		}
+
+		if got.Returning != test.returning {
+			t.Errorf("%s: need returning %v, got %v", test.name, test.returning, got.Returning)
+		}
+
+		if got.ReturnsRows() != test.rows {
+			t.Errorf("%s: need rows %v, got %v", test.name, test.rows, got.ReturnsRows())
+		}
+	}
+}
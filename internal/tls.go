@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// NewTLSConfig creates the client TLS configuration for a mode of db.TLSConfig.
+//
+// As with libpq, require works as verify-ca when a CA is given.
+// Verifying only the CA is done by hand, since the standard library verifies the host name along with the CA.
+func NewTLSConfig(mode, caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in '%s'", caFile)
+		}
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	// like libpq, require verifies the CA when one is given
+	if mode == "require" && caFile != "" {
+		mode = "verify-ca"
+	}
+
+	switch mode {
+	case "require":
+		cfg.InsecureSkipVerify = true
+
+	case "verify-ca":
+		roots := cfg.RootCAs
+
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyCA(rawCerts, roots)
+		}
+
+	case "verify-full":
+
+	default:
+		return nil, fmt.Errorf("tls mode '%s' does not use tls", mode)
+	}
+
+	return cfg, nil
+}
+
+// verifyCA verifies that the certificate chain sent by the server is signed by one of roots,
+// or by a CA of the system when roots is nil.
+func verifyCA(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("server did not send a certificate")
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+
+		certs[i] = cert
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(opts)
+
+	return err
+}
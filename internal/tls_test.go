@@ -0,0 +1,159 @@
+package internal_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+// testCert is a certificate along with its private key.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newCert creates a certificate for name signed by parent, or a self signed CA certificate when parent is nil.
+func newCert(t *testing.T, name string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Cannot create key. Error: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		tmpl.DNSNames = []string{name}
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("Cannot create certificate. Error: %v", err)
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write writes the certificate and the key of c as PEM files in dir and returns their paths.
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+
+	keyDer, _ := x509.MarshalECPrivateKey(c.key)
+
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+
+	return certFile, keyFile
+}
+
+// TestNewTLSConfig tests verifying the server certificate for each mode.
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	ca := newCert(t, "Test CA", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+
+	otherCA := newCert(t, "Other CA", nil)
+	otherCAFile, _ := otherCA.write(t, dir, "other-ca")
+
+	server := newCert(t, "localhost", ca)
+	client := newCert(t, "client", ca)
+	clientCert, clientKey := client.write(t, dir, "client")
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{server.der}, PrivateKey: server.key}},
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		ClientCAs:    clientCAs,
+	})
+	if err != nil {
+		t.Fatalf("Cannot listen. Error: %v", err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	tests := []struct {
+		name       string
+		mode       string
+		ca         string
+		cert       string
+		key        string
+		serverName string
+		ok         bool
+	}{
+		{name: "require", mode: "require", serverName: "other", ok: true},
+		{name: "require with a ca", mode: "require", ca: caFile, serverName: "other", ok: true},
+		{name: "require with another ca", mode: "require", ca: otherCAFile, serverName: "localhost"},
+		{name: "verify-ca", mode: "verify-ca", ca: caFile, serverName: "other", ok: true},
+		{name: "verify-ca with another ca", mode: "verify-ca", ca: otherCAFile, serverName: "localhost"},
+		{name: "verify-full", mode: "verify-full", ca: caFile, serverName: "localhost", ok: true},
+		{name: "verify-full with another host", mode: "verify-full", ca: caFile, serverName: "other"},
+		{name: "verify-full with system cas", mode: "verify-full", serverName: "localhost"},
+		{name: "client cert", mode: "verify-full", ca: caFile, cert: clientCert, key: clientKey, serverName: "localhost", ok: true},
+	}
+
+	for _, test := range tests {
+		cfg, err := internal.NewTLSConfig(test.mode, test.ca, test.cert, test.key, test.serverName)
+		if err != nil {
+			t.Errorf("%s: error: %v", test.name, err)
+			continue
+		}
+
+		conn, err := tls.Dial("tcp", l.Addr().String(), cfg)
+		if err == nil {
+			conn.Close()
+		}
+
+		if test.ok && err != nil {
+			t.Errorf("%s: need no error, got %v", test.name, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("%s: need error, got nil", test.name)
+		}
+	}
+
+	// missing files
+	_, err = internal.NewTLSConfig("verify-full", filepath.Join(dir, "missing.crt"), "", "", "localhost")
+	if err == nil {
+		t.Errorf("Need error, got nil")
+	}
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Tx is a database transaction bound to a context under TxKey.
+//
+// Nested calls to WrapInTx() share the same database transaction, each one level deeper than its parent.
+// The outermost level owns the transaction and is the one that begins and commits it,
+// while the nested levels use savepoints.
+type Tx struct {
+	Tx    *sql.Tx
+	opts  TxOptions
+	depth int
+}
+
+// TxOptions holds the options used to start a transaction.
+//
+// This has the same fields as db.TxOptions so that one can be converted to the other.
+type TxOptions struct {
+	Isolation  sql.IsolationLevel
+	ReadOnly   bool
+	Deferrable bool
+}
+
+// CheckNestedTx checks whether a transaction using opts can be nested inside the transaction attached to the context.
+//
+// A nested transaction cannot use a stricter isolation level than the enclosing transaction,
+// nor be read only or deferrable when the enclosing transaction is not. The default isolation level is treated as the weakest,
+// since it differs from one database to another.
+func CheckNestedTx(ctx context.Context, opts TxOptions) error {
+	parent, ok := ctx.Value(TxKey).(*Tx)
+	if !ok {
+		return nil
+	}
+
+	if opts.Isolation != sql.LevelDefault && opts.Isolation > parent.opts.Isolation {
+		return fmt.Errorf("cannot start a %s transaction inside a %s transaction", opts.Isolation, parent.opts.Isolation)
+	}
+
+	// a savepoint cannot stop the enclosing transaction from writing
+	if opts.ReadOnly && !parent.opts.ReadOnly {
+		return fmt.Errorf("cannot start a read only transaction inside a transaction that is not read only")
+	}
+
+	if opts.Deferrable && !parent.opts.Deferrable {
+		return fmt.Errorf("cannot start a deferrable transaction inside a transaction that is not deferrable")
+	}
+
+	return nil
+}
+
+// AttachTx attaches a transaction to the context.
+//
+// When the context already has a transaction, a savepoint is created in it and the returned context
+// holds the same transaction one level deeper. Otherwise a new transaction is started using begin,
+// which is expected to apply opts.
+//
+// Use CheckNestedTx() beforehand to make sure opts are compatible with an existing transaction.
+func AttachTx(ctx context.Context, opts TxOptions, begin func(ctx context.Context) (*sql.Tx, error)) (context.Context, *Tx, error) {
+	if parent, ok := ctx.Value(TxKey).(*Tx); ok {
+		tx := &Tx{
+			Tx:    parent.Tx,
+			opts:  parent.opts,
+			depth: parent.depth + 1,
+		}
+
+		if _, err := tx.Tx.ExecContext(ctx, "SAVEPOINT "+tx.savepoint()); err != nil {
+			return nil, nil, err
+		}
+
+		return context.WithValue(ctx, TxKey, tx), tx, nil
+	}
+
+	sqlTx, err := begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx := &Tx{
+		Tx:   sqlTx,
+		opts: opts,
+	}
+
+	return context.WithValue(ctx, TxKey, tx), tx, nil
+}
+
+// Nested tells whether the transaction is nested inside another transaction.
+func (tx *Tx) Nested() bool {
+	return tx.depth > 0
+}
+
+// Commit commits the transaction, or releases the savepoint when the transaction is nested.
+func (tx *Tx) Commit(ctx context.Context) error {
+	if tx.Nested() {
+		_, err := tx.Tx.ExecContext(ctx, "RELEASE SAVEPOINT "+tx.savepoint())
+		return err
+	}
+
+	return tx.Tx.Commit()
+}
+
+// Rollback rolls back the transaction, or rolls back to the savepoint when the transaction is nested.
+//
+// Rolling back to a savepoint undoes only the work done at that level and deeper,
+// leaving the outer transaction usable. This is done even when the context of the nested level is cancelled,
+// since the outer transaction may still go on to commit.
+//
+// A transaction that has already been rolled back because its context was cancelled is not reported as an error.
+func (tx *Tx) Rollback() error {
+	var err error
+	if tx.Nested() {
+		_, err = tx.Tx.ExecContext(context.Background(), "ROLLBACK TO SAVEPOINT "+tx.savepoint())
+	} else {
+		err = tx.Tx.Rollback()
+	}
+
+	if errors.Is(err, sql.ErrTxDone) {
+		return nil
+	}
+
+	return err
+}
+
+// savepoint returns the name of the savepoint of the transaction.
+//
+// Sibling nested transactions run one after the other, so reusing the same name at the same depth is safe.
+func (tx *Tx) savepoint() string {
+	return fmt.Sprintf("sp_%d", tx.depth)
+}
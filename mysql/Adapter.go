@@ -3,43 +3,78 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"regexp"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
 	// database driver for mysql
-	_ "github.com/go-sql-driver/mysql"
+	mysqldriver "github.com/go-sql-driver/mysql"
 
 	"github.com/kosatnkn/db"
 	"github.com/kosatnkn/db/internal"
 )
 
+const (
+	// maxPlaceholders is the maximum number of placeholders allowed in a prepared statement.
+	maxPlaceholders = 65535
+
+	// maxPacket is the largest packet the driver sends when max_allowed_packet is not set in the connection string.
+	maxPacket = 64 << 20
+)
+
+// tlsCount is used to give each registered TLS configuration a unique name.
+var tlsCount uint64
+
 // Adapter is used to communicate with a MySQL/MariaDB database.
 type Adapter struct {
-	cfg      Config
-	pool     *sql.DB
-	pqPrefix string
+	cfg     Config
+	pool    *sql.DB
+	tlsName string
 }
 
 // NewAdapter creates a new MySQL adapter instance.
 func NewAdapter(cfg Config) (db.AdapterInterface, error) {
-	connString := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
-		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.Database)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
-	db, err := sql.Open("mysql", connString)
+	cfg = cfg.withDefaults()
+
+	// the driver refers to custom TLS configurations by the name they are registered with
+	var tlsName string
+	if cfg.TLS.Enabled() {
+		tlsCfg, err := internal.NewTLSConfig(cfg.TLS.Mode, cfg.TLS.CACert, cfg.TLS.Cert, cfg.TLS.Key, cfg.Host)
+		if err != nil {
+			return nil, fmt.Errorf("mysql-adapter: %v", err)
+		}
+
+		tlsName = fmt.Sprintf("kosatnkn-db-%d", atomic.AddUint64(&tlsCount, 1))
+		if err := mysqldriver.RegisterTLSConfig(tlsName, tlsCfg); err != nil {
+			return nil, fmt.Errorf("mysql-adapter: %v", err)
+		}
+	}
+
+	db, err := sql.Open("mysql", cfg.dsn(tlsName))
 	if err != nil {
-		return nil, err
+		if tlsName != "" {
+			mysqldriver.DeregisterTLSConfig(tlsName)
+		}
+		// errors in the connection string may quote the password
+		return nil, internal.RedactError(fmt.Errorf("mysql-adapter: %v", err), cfg.Password)
 	}
 
 	// pool configurations
 	db.SetMaxOpenConns(cfg.PoolSize)
-	//db.SetMaxIdleConns(2)
-	//db.SetConnMaxLifetime(time.Hour)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	a := &Adapter{
-		cfg:      cfg,
-		pool:     db,
-		pqPrefix: "?",
+		cfg:     cfg,
+		pool:    db,
+		tlsName: tlsName,
 	}
 
 	// check whether the db is accessible
@@ -57,7 +92,7 @@ func (a *Adapter) Ping() error {
 
 // Query runs a query and returns the result.
 func (a *Adapter) Query(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
-	convertedQuery, placeholders := a.convertQuery(query)
+	convertedQuery, placeholders := a.convertQuery(query, params)
 
 	reorderedParams, err := a.reorderParameters(params, placeholders)
 	if err != nil {
@@ -70,9 +105,9 @@ func (a *Adapter) Query(ctx context.Context, query string, params map[string]int
 	}
 	defer stmt.Close()
 
-	// check whether the query is a select statement
-	if a.isSelect(convertedQuery) {
-		rows, err := stmt.Query(reorderedParams...)
+	// check whether the query returns rows
+	if a.statement(convertedQuery).ReturnsRows() {
+		rows, err := stmt.QueryContext(ctx, reorderedParams...)
 		if err != nil {
 			return nil, err
 		}
@@ -80,7 +115,7 @@ func (a *Adapter) Query(ctx context.Context, query string, params map[string]int
 		return a.prepareDataSet(rows)
 	}
 
-	result, err := stmt.Exec(reorderedParams...)
+	result, err := stmt.ExecContext(ctx, reorderedParams...)
 	if err != nil {
 		return nil, err
 	}
@@ -88,113 +123,280 @@ func (a *Adapter) Query(ctx context.Context, query string, params map[string]int
 	return a.prepareResultSet(result)
 }
 
+// QueryEach runs a query and calls fn with each row of the result one at a time,
+// without holding the whole result in memory.
+//
+// Iteration stops at the first error returned by fn and that error is returned.
+// Inside a transaction fn should not run other queries, since the connection is busy until all rows are read.
+func (a *Adapter) QueryEach(ctx context.Context, query string, params map[string]interface{}, fn func(row map[string]interface{}) error) error {
+	convertedQuery, placeholders := a.convertQuery(query, params)
+
+	reorderedParams, err := a.reorderParameters(params, placeholders)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := a.prepareStatement(ctx, convertedQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, reorderedParams...)
+	if err != nil {
+		return err
+	}
+
+	return a.scanRows(rows, fn)
+}
+
 // QueryBulk runs a query using an array of parameters and return the combined result.
 //
 // This query is intended to do bulk INSERTS, UPDATES and DELETES.
 // Using this for SELECTS will result in an error.
+//
+// For inserts the last insert id of each set of parameters is returned in insertion order as last_insert_ids.
 func (a *Adapter) QueryBulk(ctx context.Context, query string, params []map[string]interface{}) ([]map[string]interface{}, error) {
-	convertedQuery, placeholders := a.convertQuery(query)
+	convertedQuery, _ := a.convertQuery(query, nil)
+
+	st := a.statement(convertedQuery)
 
 	// check whether the query is a select statement
-	if a.isSelect(convertedQuery) {
+	if st.Reads() {
 		return nil, fmt.Errorf("mysql-adapter: select queries are not allowed. use Query() instead")
 	}
 
-	stmt, err := a.prepareStatement(ctx, convertedQuery)
-	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
+	// slice parameters can change the converted query from one set of parameters to the next,
+	// so statements are prepared as and when they are needed
+	stmts := internal.NewStmtCache(func(q string) (*sql.Stmt, error) {
+		return a.prepareStatement(ctx, q)
+	})
+	defer stmts.Close()
+
+	isInsert := a.isInsert(st)
 
 	var lastID int64
 	var affRows int64
+	lastIDs := make([]int64, 0)
 
 	for _, pms := range params {
+		convertedQuery, placeholders := a.convertQuery(query, pms)
+
 		reorderedParams, err := a.reorderParameters(pms, placeholders)
 		if err != nil {
 			return nil, err
 		}
 
-		result, err := stmt.Exec(reorderedParams...)
+		stmt, err := stmts.Get(convertedQuery)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := stmt.ExecContext(ctx, reorderedParams...)
 		if err != nil {
 			return nil, err
 		}
 
 		lastID, _ = result.LastInsertId()
+		if isInsert {
+			lastIDs = append(lastIDs, lastID)
+		}
 		ar, _ := result.RowsAffected()
 		affRows += ar
 	}
 
-	return a.formatResultSet(lastID, affRows), nil
+	return a.formatBulkResultSet(lastID, lastIDs, affRows), nil
+}
+
+// InsertBatch runs a single row insert query for an array of parameters and return the combined result.
+//
+// The row of values of the query is repeated for as many sets of parameters as fit in to a single statement
+// of at most 65535 placeholders and max_allowed_packet bytes.
+//
+// The insert ids are worked out from the first id generated by each statement and auto_increment_increment.
+// This holds for plain inserts, but not for INSERT IGNORE or ON DUPLICATE KEY UPDATE where rows may be skipped.
+func (a *Adapter) InsertBatch(ctx context.Context, query string, params []map[string]interface{}) ([]map[string]interface{}, error) {
+	bq, err := internal.ParseBatchQuery(query, internal.MySQL)
+	if err != nil {
+		return nil, fmt.Errorf("mysql-adapter: %v", err)
+	}
+
+	maxBytes, increment, err := a.batchLimits(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// all chunks but the last have the same number of rows, so they share a statement
+	stmts := internal.NewStmtCache(func(q string) (*sql.Stmt, error) {
+		return a.prepareStatement(ctx, q)
+	})
+	defer stmts.Close()
+
+	var lastID int64
+	var affRows int64
+	lastIDs := make([]int64, 0, len(params))
+
+	for _, rows := range bq.Split(params, maxPlaceholders, maxBytes) {
+		batchQuery, batchParams, err := bq.Build(rows)
+		if err != nil {
+			return nil, fmt.Errorf("mysql-adapter: %v", err)
+		}
+
+		convertedQuery, placeholders := a.convertQuery(batchQuery, batchParams)
+
+		reorderedParams, err := a.reorderParameters(batchParams, placeholders)
+		if err != nil {
+			return nil, err
+		}
+
+		stmt, err := stmts.Get(convertedQuery)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := stmt.ExecContext(ctx, reorderedParams...)
+		if err != nil {
+			return nil, err
+		}
+
+		// the last insert id of a multi row insert is the id of its first row
+		id, _ := result.LastInsertId()
+		for i := range rows {
+			lastID = id + int64(i)*increment
+			lastIDs = append(lastIDs, lastID)
+		}
+
+		ar, _ := result.RowsAffected()
+		affRows += ar
+	}
+
+	return a.formatBulkResultSet(lastID, lastIDs, affRows), nil
 }
 
 // WrapInTx runs the content of the function in a single transaction.
+//
+// Nested calls run inside the transaction of the outermost call using savepoints.
+// A failing nested call only rolls back the work done inside it,
+// so that the outer function can recover from the failure.
+//
+// Only the outermost call commits the transaction. An error committing or rolling back is returned as a db.TxError.
 func (a *Adapter) WrapInTx(ctx context.Context, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	return a.WrapInTxWithOptions(ctx, nil, fn)
+}
+
+// WrapInTxWithOptions runs the content of the function in a single transaction started using opts.
+//
+// The default options of the database are used when opts is nil.
+// A nested call requesting a stricter isolation level than the enclosing transaction,
+// or a read only transaction inside one that is not, results in an error.
+func (a *Adapter) WrapInTxWithOptions(ctx context.Context, opts *db.TxOptions, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
 	// attach a transaction to context
-	ctx, err := a.attachTx(ctx)
+	ctx, tx, err := a.attachTx(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	// get a reference to the attached transaction
-	tx := ctx.Value(internal.TxKey).(*sql.Tx)
-
 	// run function
 	res, err := fn(ctx)
 
 	// decide whether to commit or rollback
 	//
-	// Here we deliberately avoid catching errors from Commit() and Rollback().
-	// This is because the sql package does not give a method to check whether
-	// a transaction has already completed or not.
-	//
-	// When executing nested operations in a single transaction, either the leaf operation or the
-	// earliest failing operation of the operation tree will close the transaction.
-	// Since all operations prior to that operation also tries to close the transaction
-	// it will always result in an error.
-	// If we catch errors from Commit() and Rollback(), nested transactions
-	// will always fail because of this.
+	// Failing to finish the transaction is reported as a db.TxError,
+	// which also carries the error returned by the function if there was one.
 	if err != nil {
-		tx.Rollback()
+		if txErr := tx.Rollback(); txErr != nil {
+			return nil, &db.TxError{Err: err, TxErr: txErr}
+		}
+
 		return nil, err
 	}
 
-	tx.Commit()
+	if txErr := tx.Commit(ctx); txErr != nil {
+		return nil, &db.TxError{TxErr: txErr}
+	}
 
 	return res, nil
 }
 
+// WrapInTxWithRetry runs the content of the function in a single transaction started using opts,
+// running it again when the transaction fails due to a deadlock or a lock wait timeout.
+//
+// Only the outermost transaction is retried, since a failure at any level rolls back the whole transaction.
+// A nested call behaves the same as WrapInTxWithOptions().
+// Use db.TxAttempt() to get the attempt number inside the function.
+func (a *Adapter) WrapInTxWithRetry(ctx context.Context, opts *db.TxOptions, policy db.RetryPolicy, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if _, ok := ctx.Value(internal.TxKey).(*internal.Tx); ok {
+		return a.WrapInTxWithOptions(ctx, opts, fn)
+	}
+
+	return internal.Retry(ctx, internal.RetryPolicy(policy), a.isRetryable, func(ctx context.Context) (interface{}, error) {
+		return a.WrapInTxWithOptions(ctx, opts, fn)
+	})
+}
+
 // Destruct will close the MySQL adapter releasing all resources.
 func (a *Adapter) Destruct() error {
+	if a.tlsName != "" {
+		mysqldriver.DeregisterTLSConfig(a.tlsName)
+	}
+
 	return a.pool.Close()
 }
 
-// isSelect checks whether q is a select query.
-func (a *Adapter) isSelect(q string) bool {
-	return strings.ToLower(q[:6]) == "select"
+// statement finds the kind of the query q.
+func (a *Adapter) statement(q string) internal.Statement {
+	return internal.Classify(q, internal.MySQL)
+}
+
+// isInsert checks whether st is an insert statement.
+func (a *Adapter) isInsert(st internal.Statement) bool {
+	return st.Command == "insert" || st.Command == "replace"
+}
+
+// isRetryable checks whether err is caused by a deadlock (1213) or a lock wait timeout (1205).
+func (a *Adapter) isRetryable(err error) bool {
+	// A failure that rolls back the whole transaction also drops its savepoints, so rolling back
+	// the savepoint of a nested call fails as well. errors.As() only looks at that rollback error
+	// of a db.TxError, so the error returned by the wrapped function is checked separately.
+	var txErr *db.TxError
+	if errors.As(err, &txErr) && txErr.Err != nil && a.isRetryable(txErr.Err) {
+		return true
+	}
+
+	var mysqlErr *mysqldriver.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+
+	return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
 }
 
 // attachTx attaches a database transaction to the context.
 //
+// The transaction is bound to the context, so that it is rolled back when the context is cancelled.
+//
 // This will first check to see whether there is a transaction already in the context.
 // Having a transaction already attached to context probably means that the calling function
 // has been wrapped in a transaction in a previous stage.
-// When this is the case use the existing attached transaction.
-// Otherwise create a new transaction and attach.
-func (a *Adapter) attachTx(ctx context.Context) (context.Context, error) {
-	// check tx altready exists
-	tx := ctx.Value(internal.TxKey)
-	if tx != nil {
-		return ctx, nil
+// When this is the case a savepoint is created in the existing attached transaction.
+// Otherwise create a new transaction using opts and attach.
+func (a *Adapter) attachTx(ctx context.Context, opts *db.TxOptions) (context.Context, *internal.Tx, error) {
+	var o internal.TxOptions
+	if opts != nil {
+		o = internal.TxOptions(*opts)
 	}
 
-	// attach new tx
-	tx, err := a.pool.Begin()
-	if err != nil {
-		return nil, err
+	if o.Deferrable {
+		return nil, nil, fmt.Errorf("mysql-adapter: deferrable transactions are not supported")
+	}
+
+	if err := internal.CheckNestedTx(ctx, o); err != nil {
+		return nil, nil, fmt.Errorf("mysql-adapter: %v", err)
 	}
 
-	return context.WithValue(ctx, internal.TxKey, tx), nil
+	return internal.AttachTx(ctx, o, func(ctx context.Context) (*sql.Tx, error) {
+		return a.pool.BeginTx(ctx, &sql.TxOptions{Isolation: o.Isolation, ReadOnly: o.ReadOnly})
+	})
 }
 
 // convertQuery converts the named parameter query to a placeholder query that MySQL library understands.
@@ -206,24 +408,41 @@ func (a *Adapter) attachTx(ctx context.Context) (context.Context, error) {
 // UPDATE tbl SET col1 = ?, col2 = ? WHERE col3 = ?
 // DELETE FROM tbl WHERE col = ?
 //
+// A named parameter having a slice value in params is expanded to a placeholder for each element.
+//
+// SELECT * FROM tbl WHERE col IN (?vals) -> SELECT * FROM tbl WHERE col IN (?, ?, ?)
+//
 // This will return the query and a slice of strings containing named parameter name in the order that they are found
-// in the query.
-func (a *Adapter) convertQuery(query string) (string, []string) {
+// in the query. Named parameters inside string literals, quoted identifiers and comments are left as they are.
+func (a *Adapter) convertQuery(query string, params map[string]interface{}) (string, []string) {
 	query = strings.TrimSpace(query)
-	exp := regexp.MustCompile(`\` + a.pqPrefix + `\w+`)
 
-	namedParams := exp.FindAllString(query, -1)
+	var namedParams []string
 
-	for i := 0; i < len(namedParams); i++ {
-		namedParams[i] = strings.TrimPrefix(namedParams[i], a.pqPrefix)
-	}
+	query = internal.ReplaceParams(query, internal.MySQL, func(name string) string {
+		namedParams = append(namedParams, name)
 
-	query = exp.ReplaceAllString(query, "?")
+		return a.placeholders(params[name])
+	})
 
 	return query, namedParams
 }
 
+// placeholders returns the placeholders needed to bind the value.
+//
+// A slice value needs a placeholder for each of its elements.
+func (a *Adapter) placeholders(value interface{}) string {
+	elems, ok := internal.ExpandSlice(value)
+	if !ok {
+		return "?"
+	}
+
+	return strings.TrimSuffix(strings.Repeat("?, ", len(elems)), ", ")
+}
+
 // reorderParameters reorders the parameters map in the order of named parameters slice.
+//
+// Slice parameters are expanded in to their elements to match the placeholders created by convertQuery().
 func (a *Adapter) reorderParameters(params map[string]interface{}, namedParams []string) ([]interface{}, error) {
 	var reorderedParams []interface{}
 
@@ -235,33 +454,83 @@ func (a *Adapter) reorderParameters(params map[string]interface{}, namedParams [
 			return nil, fmt.Errorf("mysql-adapter: parameter '%s' is missing", param)
 		}
 
+		// expand slices in to individual parameters
+		if elems, ok := internal.ExpandSlice(paramValue); ok {
+			if len(elems) == 0 {
+				return nil, fmt.Errorf("mysql-adapter: parameter '%s' is an empty slice", param)
+			}
+
+			reorderedParams = append(reorderedParams, elems...)
+			continue
+		}
+
 		reorderedParams = append(reorderedParams, paramValue)
 	}
 
 	return reorderedParams, nil
 }
 
+// batchLimits returns the maximum number of bytes in a statement and the step between auto increment values.
+func (a *Adapter) batchLimits(ctx context.Context) (int, int64, error) {
+	q := "SELECT @@max_allowed_packet, @@auto_increment_increment"
+
+	var row *sql.Row
+	if tx, ok := ctx.Value(internal.TxKey).(*internal.Tx); ok {
+		row = tx.Tx.QueryRowContext(ctx, q)
+	} else {
+		row = a.pool.QueryRowContext(ctx, q)
+	}
+
+	var packet, increment int64
+	if err := row.Scan(&packet, &increment); err != nil {
+		return 0, 0, err
+	}
+
+	if packet > maxPacket {
+		packet = maxPacket
+	}
+
+	// leave room for the headers of the packet
+	return int(packet) - 1024, increment, nil
+}
+
 // prepareStatement creates a prepared statement using the query.
 //
 // Checks whether there is a transaction attached to the context.
 // If so use that transaction to prepare statement else use the pool.
 func (a *Adapter) prepareStatement(ctx context.Context, query string) (*sql.Stmt, error) {
-	tx := ctx.Value(internal.TxKey)
-	if tx != nil {
-		return tx.(*sql.Tx).Prepare(query)
+	if tx, ok := ctx.Value(internal.TxKey).(*internal.Tx); ok {
+		return tx.Tx.PrepareContext(ctx, query)
 	}
 
-	return a.pool.Prepare(query)
+	return a.pool.PrepareContext(ctx, query)
 }
 
 // prepareDataSet creates a dataset using the output of a SELECT statement.
+func (a *Adapter) prepareDataSet(rows *sql.Rows) ([]map[string]interface{}, error) {
+	var data []map[string]interface{}
+
+	err := a.scanRows(rows, func(row map[string]interface{}) error {
+		data = append(data, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// scanRows calls fn with each row of the output of a SELECT statement, stopping at the first error returned by fn.
+//
+// Each row is a new map, so fn can keep it.
 //
 // Source: https://kylewbanks.com/blog/query-result-to-map-in-golang
-func (a *Adapter) prepareDataSet(rows *sql.Rows) ([]map[string]interface{}, error) {
+func (a *Adapter) scanRows(rows *sql.Rows, fn func(row map[string]interface{}) error) error {
 	defer rows.Close()
 
-	var data []map[string]interface{}
 	cols, _ := rows.Columns()
+	types, _ := rows.ColumnTypes()
 
 	// create a slice of interface{}'s to represent each column
 	// and a second slice to contain pointers to each item in the columns slice
@@ -276,7 +545,7 @@ func (a *Adapter) prepareDataSet(rows *sql.Rows) ([]map[string]interface{}, erro
 		// scan the result into the column pointers
 		err := rows.Scan(columnPointers...)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		// create our map, and retrieve the value for each column from the pointers slice
@@ -286,12 +555,63 @@ func (a *Adapter) prepareDataSet(rows *sql.Rows) ([]map[string]interface{}, erro
 		for i, colName := range cols {
 			val := columnPointers[i].(*interface{})
 			row[colName] = *val
+
+			if !a.cfg.RawValues && types != nil {
+				row[colName] = a.convertValue(*val, types[i])
+			}
 		}
 
-		data = append(data, row)
+		if err := fn(row); err != nil {
+			return err
+		}
 	}
 
-	return data, nil
+	// iteration stops early when an error occurs, as in the case of a cancelled context
+	return rows.Err()
+}
+
+// convertValue converts a []byte value returned by the driver to a Go type based on the type of the column.
+//
+// Text, DECIMAL and TIME columns result in a string, keeping the exact value of decimals.
+// Integer and floating point columns result in an int64 (or a uint64 when unsigned) and a float64,
+// while BIT columns result in an int64. Binary columns like BLOB remain a []byte.
+func (a *Adapter) convertValue(value interface{}, ct *sql.ColumnType) interface{} {
+	b, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+
+	typ := ct.DatabaseTypeName()
+
+	switch {
+	case typ == "BINARY" || typ == "VARBINARY" || typ == "GEOMETRY" || strings.HasSuffix(typ, "BLOB"):
+		return b
+
+	case typ == "BIT":
+		var n int64
+		for _, c := range b {
+			n = n<<8 | int64(c)
+		}
+
+		return n
+
+	case strings.HasPrefix(typ, "UNSIGNED "):
+		if n, err := strconv.ParseUint(string(b), 10, 64); err == nil {
+			return n
+		}
+
+	case strings.HasSuffix(typ, "INT"):
+		if n, err := strconv.ParseInt(string(b), 10, 64); err == nil {
+			return n
+		}
+
+	case typ == "FLOAT" || typ == "DOUBLE":
+		if f, err := strconv.ParseFloat(string(b), 64); err == nil {
+			return f
+		}
+	}
+
+	return string(b)
 }
 
 // prepareResultSet creates a resultset using the result of Exec()
@@ -318,3 +638,12 @@ func (a *Adapter) formatResultSet(id, aff int64) []map[string]interface{} {
 		internal.LastInsertID: id,
 	})
 }
+
+// formatBulkResultSet creates a resultset using last insert id, the insert ids of all sets of parameters
+// and affected rows.
+func (a *Adapter) formatBulkResultSet(id int64, ids []int64, aff int64) []map[string]interface{} {
+	data := a.formatResultSet(id, aff)
+	data[0][internal.LastInsertIDs] = ids
+
+	return data
+}
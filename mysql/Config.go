@@ -1,5 +1,24 @@
 package mysql
 
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+
+	"github.com/kosatnkn/db"
+	"github.com/kosatnkn/db/internal"
+)
+
+// Defaults used for the pool settings of Config that are not set.
+const (
+	DefaultMaxIdleConns    = 2
+	DefaultConnMaxLifetime = time.Hour
+	DefaultConnectTimeout  = 10 * time.Second
+)
+
 // Config contains common database configurations for all database connections.
 type Config struct {
 	Host     string `yaml:"host"`
@@ -9,4 +28,140 @@ type Config struct {
 	Password string `yaml:"password"`
 	PoolSize int    `yaml:"pool_size"`
 	Check    bool   `yaml:"check"`
+
+	// MaxIdleConns is the maximum number of idle connections kept in the pool. Defaults to DefaultMaxIdleConns.
+	MaxIdleConns int `yaml:"max_idle_conns"`
+
+	// ConnMaxLifetime is the maximum time a connection is reused for. Defaults to DefaultConnMaxLifetime.
+	// A negative value means there is no limit.
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+
+	// ConnMaxIdleTime is the maximum time a connection is kept idle before it is closed.
+	// Set this below the idle timeout of load balancers and firewalls in between, so that connections they
+	// drop are not reused. There is no limit when this is zero or negative.
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+
+	// ConnectTimeout is the maximum time to wait while connecting. Defaults to DefaultConnectTimeout.
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+
+	// TLS contains the settings used to connect over TLS. Connections do not use TLS when TLS.Mode is not set.
+	TLS db.TLSConfig `yaml:"tls"`
+
+	// Params are additional connection parameters passed to the driver as they are, like `charset`, `collation` or `loc`.
+	// They take precedence over the parameters set using other fields.
+	Params map[string]string `yaml:"params"`
+
+	// RawValues keeps column values as returned by the driver, which is []byte for most column types
+	// including DATE and DATETIME. By default values are converted to Go types comparable to those of other adapters.
+	RawValues bool `yaml:"raw_values"`
+}
+
+// withDefaults returns a copy of cfg with defaults set for the pool settings that are not set.
+func (cfg Config) withDefaults() Config {
+	if cfg.MaxIdleConns == 0 {
+		cfg.MaxIdleConns = DefaultMaxIdleConns
+	}
+
+	if cfg.ConnMaxLifetime == 0 {
+		cfg.ConnMaxLifetime = DefaultConnMaxLifetime
+	}
+
+	if cfg.ConnectTimeout == 0 {
+		cfg.ConnectTimeout = DefaultConnectTimeout
+	}
+
+	return cfg
+}
+
+// Validate checks whether the settings needed to connect are set and whether all settings are within range.
+//
+// All the problems found are returned at once as a *db.ConfigError.
+func (cfg Config) Validate() error {
+	var problems []string
+
+	if cfg.Host == "" {
+		problems = append(problems, "host is required")
+	}
+
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("port should be between 1 and 65535, got %d", cfg.Port))
+	}
+
+	if cfg.Database == "" {
+		problems = append(problems, "database is required")
+	}
+
+	if cfg.User == "" {
+		problems = append(problems, "user is required")
+	}
+
+	// a pool size of 0 lets the pool open any number of connections
+	if cfg.PoolSize < 1 {
+		problems = append(problems, fmt.Sprintf("pool_size should be greater than 0, got %d", cfg.PoolSize))
+	}
+
+	if cfg.MaxIdleConns < 0 {
+		problems = append(problems, "max_idle_conns cannot be negative")
+	} else if cfg.PoolSize > 0 && cfg.MaxIdleConns > cfg.PoolSize {
+		problems = append(problems, "max_idle_conns cannot be greater than pool_size")
+	}
+
+	if cfg.ConnectTimeout < 0 {
+		problems = append(problems, "connect_timeout cannot be negative")
+	}
+
+	if err := cfg.TLS.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		return &db.ConfigError{Adapter: "mysql-adapter", Problems: problems}
+	}
+
+	return nil
+}
+
+// String returns cfg in a form that is safe to log, with the password redacted.
+func (cfg Config) String() string {
+	return fmt.Sprintf("%+v", cfg.redacted())
+}
+
+// GoString returns cfg in Go syntax with the password redacted, so that %#v is safe to log as well.
+func (cfg Config) GoString() string {
+	return fmt.Sprintf("%#v", cfg.redacted())
+}
+
+// config has the fields of Config without its methods, so that it can be formatted without calling String.
+type config Config
+
+// redacted returns a copy of cfg with the password and password like params redacted.
+func (cfg Config) redacted() config {
+	if cfg.Password != "" {
+		cfg.Password = internal.Redacted
+	}
+
+	cfg.Params = internal.RedactParams(cfg.Params)
+
+	return config(cfg)
+}
+
+// dsn creates the connection string for cfg, referring to the TLS configuration registered as tlsName if any.
+func (cfg Config) dsn(tlsName string) string {
+	c := mysqldriver.NewConfig()
+
+	c.User = cfg.User
+	c.Passwd = cfg.Password
+	c.Net = "tcp"
+	c.Addr = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+	c.DBName = cfg.Database
+	c.Timeout = cfg.ConnectTimeout
+	c.TLSConfig = tlsName
+
+	// parse DATE and DATETIME values in to time.Time unless raw values are asked for
+	c.ParseTime = !cfg.RawValues
+
+	// the driver writes these after the other parameters, so they win when the connection string is parsed
+	c.Params = cfg.Params
+
+	return c.FormatDSN()
 }
@@ -0,0 +1,44 @@
+package mysql
+
+import (
+	"fmt"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+// LoadConfig reads the configuration from the YAML file at path, laid out like config/mysql.yaml,
+// and overlays it with environment variables prefixed with DB_.
+//
+// Each setting can be overridden by an environment variable named after its key in upper case, like
+// DB_PASSWORD for password and DB_TLS_MODE for the mode of tls.
+// Params are set using DB_PARAMS in URL query format, like `charset=utf8mb4&loc=Local`.
+// A variable with a _FILE suffix like DB_PASSWORD_FILE reads the value from the file at the path it has,
+// so that secrets mounted by Docker or Kubernetes can be used.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+
+	if err := internal.LoadYAML(path, &cfg); err != nil {
+		return Config{}, fmt.Errorf("mysql-adapter: %v", err)
+	}
+
+	return cfg.fromEnv(internal.EnvPrefix)
+}
+
+// ConfigFromEnv reads the configuration from environment variables prefixed with prefix
+// the same way LoadConfig does, like `prefix + "PASSWORD"` for password.
+func ConfigFromEnv(prefix string) (Config, error) {
+	return Config{}.fromEnv(prefix)
+}
+
+// fromEnv overlays cfg with environment variables prefixed with prefix and validates the result.
+func (cfg Config) fromEnv(prefix string) (Config, error) {
+	if err := internal.ReadEnv(prefix, &cfg); err != nil {
+		return Config{}, fmt.Errorf("mysql-adapter: %v", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,235 @@
+package mysql
+
+import (
+	"bufio"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+
+	"github.com/kosatnkn/db/internal"
+)
+
+// readerCount is used to give each registered reader handler a unique name.
+var readerCount uint64
+
+// LoadData streams comma separated data read from r in to the columns of table using LOAD DATA LOCAL INFILE.
+//
+// Each line of the data is a row ending with '\n'. Values are separated by ',' and can be enclosed in '"'.
+// A backslash escapes the character that follows it and an unenclosed \N is a NULL.
+// All columns of the table are loaded when columns is empty.
+// Either all rows are loaded or none, since loading runs in a transaction.
+//
+// The server should allow loading local data, which is done by setting local_infile to ON.
+func (a *Adapter) LoadData(ctx context.Context, table string, columns []string, r io.Reader) ([]map[string]interface{}, error) {
+	// hide Close() of r from the driver, since r is owned by the caller
+	return a.load(ctx, table, columns, struct{ io.Reader }{r})
+}
+
+// LoadRows streams the rows returned by next in to the columns of table using LOAD DATA LOCAL INFILE.
+//
+// next is called until it returns io.EOF. Any other error stops loading and is returned.
+// Each row should have a value for each of the columns in the same order.
+// Either all rows are loaded or none, since loading runs in a transaction.
+//
+// The server should allow loading local data, which is done by setting local_infile to ON.
+func (a *Adapter) LoadRows(ctx context.Context, table string, columns []string, next func() ([]interface{}, error)) ([]map[string]interface{}, error) {
+	pr, pw := io.Pipe()
+
+	var rowErr error
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		w := bufio.NewWriter(pw)
+		for i := 0; ; i++ {
+			row, err := next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err == nil && len(columns) > 0 && len(row) != len(columns) {
+				err = fmt.Errorf("mysql-adapter: row %d has %d values, need %d", i, len(row), len(columns))
+			}
+			if err == nil {
+				err = a.writeRow(w, row)
+
+				// the driver stopped reading, so its own error is the one to report
+				if errors.Is(err, io.ErrClosedPipe) {
+					return
+				}
+			}
+			if err != nil {
+				rowErr = err
+				pw.CloseWithError(err)
+				return
+			}
+		}
+
+		pw.CloseWithError(w.Flush())
+	}()
+
+	res, err := a.load(ctx, table, columns, pr)
+
+	// stop writing rows when loading ended early
+	pr.Close()
+	<-done
+
+	// the error of the rows tells more than the error of the driver failing to read them
+	if rowErr != nil {
+		return nil, rowErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// load runs LOAD DATA LOCAL INFILE reading the data from r.
+//
+// The load runs in the transaction attached to the context, or in a transaction of its own when there is none.
+func (a *Adapter) load(ctx context.Context, table string, columns []string, r io.Reader) ([]map[string]interface{}, error) {
+	// the driver ends the data the same way on a read error as on the end of r, so the server would
+	// commit the rows read until then unless the load is rolled back
+	if _, ok := ctx.Value(internal.TxKey).(*internal.Tx); !ok {
+		res, err := a.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+			return a.load(ctx, table, columns, r)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return res.([]map[string]interface{}), nil
+	}
+
+	name := fmt.Sprintf("kosatnkn-db-%d", atomic.AddUint64(&readerCount, 1))
+
+	mysqldriver.RegisterReaderHandler(name, func() io.Reader {
+		return r
+	})
+	defer mysqldriver.DeregisterReaderHandler(name)
+
+	query := a.loadQuery(name, table, columns)
+
+	// LOAD DATA cannot be prepared, so it is run directly
+	tx := ctx.Value(internal.TxKey).(*internal.Tx)
+
+	result, err := tx.Tx.ExecContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.prepareResultSet(result)
+}
+
+// loadQuery creates the LOAD DATA statement reading from the reader handler registered by name.
+func (a *Adapter) loadQuery(name, table string, columns []string) string {
+	var b strings.Builder
+
+	b.WriteString("LOAD DATA LOCAL INFILE 'Reader::" + name + "' INTO TABLE " + a.quoteIdentifier(table))
+	b.WriteString(` CHARACTER SET utf8mb4 FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' ESCAPED BY '\\'`)
+	b.WriteString(` LINES TERMINATED BY '\n'`)
+
+	if len(columns) > 0 {
+		quoted := make([]string, len(columns))
+		for i, c := range columns {
+			quoted[i] = a.quoteIdentifier(c)
+		}
+
+		b.WriteString(" (" + strings.Join(quoted, ", ") + ")")
+	}
+
+	return b.String()
+}
+
+// quoteIdentifier quotes an identifier that may be qualified by a database as in database.table.
+func (a *Adapter) quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = "`" + strings.ReplaceAll(p, "`", "``") + "`"
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// writeRow writes the values of a row as a line of comma separated data.
+func (a *Adapter) writeRow(w *bufio.Writer, row []interface{}) error {
+	for i, v := range row {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+
+		if err := a.writeValue(w, v); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.WriteString("\n")
+
+	return err
+}
+
+// writeValue writes a value escaping it so that it is read back as it is.
+//
+// NULLs are written as \N and all other values are enclosed in quotes,
+// with backslashes, quotes, line breaks and NUL characters escaped using a backslash.
+func (a *Adapter) writeValue(w *bufio.Writer, v interface{}) error {
+	if valuer, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = valuer.Value(); err != nil {
+			return err
+		}
+	}
+
+	var s string
+	switch v := v.(type) {
+	case nil:
+		_, err := w.WriteString(`\N`)
+		return err
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case bool:
+		s = "0"
+		if v {
+			s = "1"
+		}
+	case time.Time:
+		s = v.UTC().Format("2006-01-02 15:04:05.999999")
+	case float32:
+		s = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		s = strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	w.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\', '"':
+			w.WriteByte('\\')
+			w.WriteByte(s[i])
+		case 0:
+			w.WriteString(`\0`)
+		case '\n':
+			w.WriteString(`\n`)
+		case '\r':
+			w.WriteString(`\r`)
+		default:
+			w.WriteByte(s[i])
+		}
+	}
+	w.WriteByte('"')
+
+	return nil
+}
@@ -0,0 +1,353 @@
+package mysql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+
+	"github.com/kosatnkn/db"
+	"github.com/kosatnkn/db/internal"
+)
+
+// benchRows is the number of rows inserted by each iteration of the benchmarks.
+const benchRows = 1000
+
+// newBatchParams creates n sets of parameters for the test table.
+func newBatchParams(n int) []map[string]interface{} {
+	params := make([]map[string]interface{}, n)
+	for i := range params {
+		params[i] = map[string]interface{}{
+			"name":     fmt.Sprintf("Name %d", i+1),
+			"password": fmt.Sprintf("pwd%d", i+1),
+		}
+	}
+
+	return params
+}
+
+// TestInsertBatch tests inserting many rows using multi row inserts.
+func TestInsertBatch(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	batch, ok := adapter.(db.BatchInsertInterface)
+	if !ok {
+		t.Fatal("Need adapter to implement db.BatchInsertInterface")
+	}
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	r, err := batch.InsertBatch(context.Background(), q, newBatchParams(3))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := 3
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	need = 3
+	got = int(r[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Last insert id: need `%d`, got `%d`", need, got)
+	}
+
+	idsNeed := "[1 2 3]"
+	idsGot := fmt.Sprintf("%v", r[0][internal.LastInsertIDs].([]int64))
+	if idsGot != idsNeed {
+		t.Errorf("Last insert ids: need `%s`, got `%s`", idsNeed, idsGot)
+	}
+
+	// check whether all data is inserted
+	cr, _ := adapter.Query(context.Background(), `select * from sample order by id`, nil)
+	if len(cr) != 3 {
+		t.Fatalf("Need 3 records, got %d records", len(cr))
+	}
+
+	cNeed := "3, Name 3, pwd3"
+	cGot := fmt.Sprintf("%d, %s, %s", int(cr[2]["id"].(int64)), cr[2]["name"], cr[2]["password"])
+	if cGot != cNeed {
+		t.Errorf("Need `%s`, got `%s`", cNeed, cGot)
+	}
+}
+
+// TestInsertBatchChunks tests inserting more rows than the number of placeholders allowed in a statement.
+func TestInsertBatchChunks(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	r, err := adapter.(db.BatchInsertInterface).InsertBatch(context.Background(), q, newBatchParams(40000))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := 40000
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	got = len(r[0][internal.LastInsertIDs].([]int64))
+	if got != need {
+		t.Errorf("Last insert ids: need `%d`, got `%d`", need, got)
+	}
+}
+
+// TestInsertBatchMissingParameter tests inserting rows without all named parameters.
+func TestInsertBatchMissingParameter(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+	params := newBatchParams(2)
+	delete(params[1], "password")
+
+	_, err := adapter.(db.BatchInsertInterface).InsertBatch(context.Background(), q, params)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := "mysql-adapter: parameter 'password' is missing"
+	got := err.Error()
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestLoadRows tests streaming rows using LOAD DATA LOCAL INFILE.
+//
+// NOTE: the server should have local_infile set to ON.
+func TestLoadRows(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	loader, ok := adapter.(db.LoadDataInterface)
+	if !ok {
+		t.Fatal("Need adapter to implement db.LoadDataInterface")
+	}
+
+	rows := [][]interface{}{
+		{"Name 1", "pwd1"},
+		{`Name "2", with \ and`, "pwd\n2"},
+	}
+
+	i := 0
+	next := func() ([]interface{}, error) {
+		if i == len(rows) {
+			return nil, io.EOF
+		}
+		i++
+
+		return rows[i-1], nil
+	}
+
+	r, err := loader.LoadRows(context.Background(), "sample", []string{"name", "password"}, next)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := 2
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	// check whether values are loaded as they are
+	cr, _ := adapter.Query(context.Background(), `select * from sample order by id`, nil)
+	if len(cr) != 2 {
+		t.Fatalf("Need 2 records, got %d records", len(cr))
+	}
+
+	cNeed := fmt.Sprintf("%s, %s", rows[1][0], rows[1][1])
+	cGot := fmt.Sprintf("%s, %s", cr[1]["name"], cr[1]["password"])
+	if cGot != cNeed {
+		t.Errorf("Need `%s`, got `%s`", cNeed, cGot)
+	}
+}
+
+// TestLoadRowsFail tests that an error returned by the row iterator stops loading.
+func TestLoadRowsFail(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	need := errors.New("cannot read row")
+	next := func() ([]interface{}, error) {
+		return nil, need
+	}
+
+	_, got := adapter.(db.LoadDataInterface).LoadRows(context.Background(), "sample", []string{"name", "password"}, next)
+	if got != need {
+		t.Errorf("Need `%v`, got `%v`", need, got)
+	}
+}
+
+// TestLoadRowsFailRollback tests that rows streamed before the row iterator fails are not loaded.
+func TestLoadRowsFailRollback(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	params := newBatchParams(benchRows)
+	need := errors.New("cannot read row")
+
+	i := 0
+	next := func() ([]interface{}, error) {
+		if i == len(params)-1 {
+			return nil, need
+		}
+		i++
+
+		return []interface{}{params[i-1]["name"], params[i-1]["password"]}, nil
+	}
+
+	_, got := adapter.(db.LoadDataInterface).LoadRows(context.Background(), "sample", []string{"name", "password"}, next)
+	if !errors.Is(got, need) {
+		t.Errorf("Need `%v`, got `%v`", need, got)
+	}
+
+	r, _ := adapter.Query(context.Background(), `select count(*) as count from sample`, nil)
+
+	cNeed := 0
+	cGot := int(r[0]["count"].(int64))
+	if cGot != cNeed {
+		t.Errorf("Need %d, got %d", cNeed, cGot)
+	}
+}
+
+// TestLoadRowsServerError tests that the error of the server is returned when it rejects the statement
+// while rows are still being written.
+func TestLoadRowsServerError(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// more rows than fit in the buffer of the row writer
+	params := newBatchParams(benchRows)
+
+	i := 0
+	next := func() ([]interface{}, error) {
+		if i == len(params) {
+			return nil, io.EOF
+		}
+		i++
+
+		return []interface{}{params[i-1]["name"], params[i-1]["password"]}, nil
+	}
+
+	_, err := adapter.(db.LoadDataInterface).LoadRows(context.Background(), "non_existant_table", []string{"name", "password"}, next)
+
+	var mysqlErr *mysqldriver.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != 1146 {
+		t.Errorf("Need error 1146 of the server, got `%v`", err)
+	}
+}
+
+// TestLoadDataTxRollback tests that data loaded inside a failing transaction is rolled back.
+func TestLoadDataTxRollback(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	data := "\"Name 1\",\"pwd1\"\n\"Name 2\",\"pwd2\"\n"
+
+	_, err := adapter.WrapInTx(context.Background(), func(ctx context.Context) (interface{}, error) {
+		r, err := adapter.(db.LoadDataInterface).LoadData(ctx, "sample", []string{"name", "password"}, strings.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		need := 2
+		got := int(r[0][internal.AffectedRows].(int64))
+		if got != need {
+			t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+		}
+
+		return adapter.Query(ctx, `select * from non_existant_table`, nil)
+	})
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	r, _ := adapter.Query(context.Background(), `select count(*) as count from sample`, nil)
+
+	need := 0
+	got := int(r[0]["count"].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+}
+
+// BenchmarkQueryBulk benchmarks inserting rows one statement at a time.
+func BenchmarkQueryBulk(b *testing.B) {
+	adapter := newDBAdapter(b)
+	defer adapter.Destruct()
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+	params := newBatchParams(benchRows)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := adapter.QueryBulk(context.Background(), q, params); err != nil {
+			b.Fatalf("Error: %v", err)
+		}
+	}
+}
+
+// BenchmarkInsertBatch benchmarks inserting rows using multi row inserts.
+func BenchmarkInsertBatch(b *testing.B) {
+	adapter := newDBAdapter(b)
+	defer adapter.Destruct()
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+	params := newBatchParams(benchRows)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := adapter.(db.BatchInsertInterface).InsertBatch(context.Background(), q, params); err != nil {
+			b.Fatalf("Error: %v", err)
+		}
+	}
+}
+
+// BenchmarkLoadRows benchmarks streaming rows using LOAD DATA LOCAL INFILE.
+func BenchmarkLoadRows(b *testing.B) {
+	adapter := newDBAdapter(b)
+	defer adapter.Destruct()
+
+	params := newBatchParams(benchRows)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := 0
+		next := func() ([]interface{}, error) {
+			if j == len(params) {
+				return nil, io.EOF
+			}
+			j++
+
+			return []interface{}{params[j-1]["name"], params[j-1]["password"]}, nil
+		}
+
+		if _, err := adapter.(db.LoadDataInterface).LoadRows(context.Background(), "sample", []string{"name", "password"}, next); err != nil {
+			b.Fatalf("Error: %v", err)
+		}
+	}
+}
@@ -2,9 +2,12 @@ package mysql_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kosatnkn/db"
 	"github.com/kosatnkn/db/internal"
@@ -21,7 +24,7 @@ import (
 //
 
 // newDBAdapter creates a new db adapter pointing to the test db.
-func newDBAdapter(t *testing.T) db.AdapterInterface {
+func newDBAdapter(t testing.TB) db.AdapterInterface {
 	cfg := mysql.Config{
 		Host:     "127.0.0.1",
 		Port:     3306,
@@ -41,7 +44,7 @@ func newDBAdapter(t *testing.T) db.AdapterInterface {
 }
 
 // clearTestTable clears all data from the test table.
-func clearTestTable(t *testing.T) {
+func clearTestTable(t testing.TB) {
 	adapter := newDBAdapter(t)
 	defer adapter.Destruct()
 
@@ -229,6 +232,329 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+// TestSelectQuotedPlaceholder tests that named parameters inside strings and comments are not converted.
+func TestSelectQuotedPlaceholder(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample(name, password) values ('?draft', ?password)`
+	params := map[string]interface{}{
+		"password": "pwd1",
+	}
+
+	_, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// select
+	q = `select * from sample where name = '?draft' -- and password = ?password`
+
+	r, err := adapter.Query(context.Background(), q, nil)
+	if err != nil {
+		t.Fatalf("Error selecting: %v", err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("Need 1 record, got %d records", len(r))
+	}
+
+	need := "?draft, pwd1"
+	got := fmt.Sprintf("%s, %s", r[0]["name"], r[0]["password"])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestSelectIn tests select query using a slice parameter in an IN clause.
+func TestSelectIn(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	ips := make([]map[string]interface{}, 0)
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 2",
+		"password": "pwd2",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 3",
+		"password": "pwd3",
+	})
+
+	_, err := adapter.QueryBulk(context.Background(), q, ips)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// select
+	q = `select * from sample where id in (?ids) and name <> ?name order by id`
+	params := map[string]interface{}{
+		"ids":  []int{1, 2, 3},
+		"name": "Name 2",
+	}
+
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error selecting: %v", err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("Need 2 records, got %d records", len(r))
+	}
+
+	need := "1, 3"
+	got := fmt.Sprintf("%d, %d", int(r[0]["id"].(int64)), int(r[1]["id"].(int64)))
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// select using an empty slice
+	params = map[string]interface{}{
+		"ids":  []int{},
+		"name": "Name 2",
+	}
+
+	_, err = adapter.Query(context.Background(), q, params)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	eNeed := "mysql-adapter: parameter 'ids' is an empty slice"
+	eGot := err.Error()
+	if eGot != eNeed {
+		t.Errorf("Need `%s`, got `%s`", eNeed, eGot)
+	}
+}
+
+// TestQueryReturnsRows tests queries with a common table expression that return rows.
+func TestQueryReturnsRows(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	ips := make([]map[string]interface{}, 0)
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 2",
+		"password": "pwd2",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 3",
+		"password": "pwd3",
+	})
+
+	_, err := adapter.QueryBulk(context.Background(), q, ips)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// select using a common table expression after a comment
+	q = `-- names other than the given one
+		with t as (select * from sample where name <> ?name) select id from t order by id`
+	params := map[string]interface{}{
+		"name": "Name 2",
+	}
+
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error selecting: %v", err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("Need 2 records, got %d records", len(r))
+	}
+}
+
+// TestQueryEach tests iterating over the rows of a query one at a time.
+func TestQueryEach(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	ips := make([]map[string]interface{}, 0)
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 2",
+		"password": "pwd2",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 3",
+		"password": "pwd3",
+	})
+
+	_, err := adapter.QueryBulk(context.Background(), q, ips)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// iterate over all rows inside a transaction
+	q = `select * from sample where id > ?id order by id`
+	params := map[string]interface{}{
+		"id": 0,
+	}
+
+	var names []string
+	_, err = adapter.WrapInTx(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return nil, adapter.QueryEach(ctx, q, params, func(row map[string]interface{}) error {
+			names = append(names, fmt.Sprintf("%s", row["name"]))
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "Name 1, Name 2, Name 3"
+	got := strings.Join(names, ", ")
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// stop early
+	errStop := errors.New("stop")
+	count := 0
+
+	err = adapter.QueryEach(context.Background(), q, params, func(row map[string]interface{}) error {
+		count++
+		if count == 2 {
+			return errStop
+		}
+
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("Need `%v`, got `%v`", errStop, err)
+	}
+	if count != 2 {
+		t.Errorf("Need 2 rows, got %d rows", count)
+	}
+
+	// the adapter should still be usable after stopping early
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(r) != 3 {
+		t.Errorf("Need 3 records, got %d records", len(r))
+	}
+}
+
+// TestColumnValues tests converting column values to Go types.
+func TestColumnValues(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+	params := map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	}
+
+	_, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	q = `select id, name, cast(1.50 as decimal(5,2)) as price, now() as created, cast('ab' as binary) as raw from sample`
+
+	r, err := adapter.Query(context.Background(), q, nil)
+	if err != nil {
+		t.Fatalf("Error selecting: %v", err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("Need 1 record, got %d records", len(r))
+	}
+
+	need := "int64, string, string, time.Time, []uint8"
+	got := fmt.Sprintf("%T, %T, %T, %T, %T", r[0]["id"], r[0]["name"], r[0]["price"], r[0]["created"], r[0]["raw"])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	need = "1.50"
+	got = r[0]["price"].(string)
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// raw values
+	cfg := mysql.Config{
+		Host:      "127.0.0.1",
+		Port:      3306,
+		Database:  "sample",
+		User:      "root",
+		Password:  "root",
+		PoolSize:  10,
+		RawValues: true,
+	}
+
+	raw, err := mysql.NewAdapter(cfg)
+	if err != nil {
+		t.Fatalf("Cannot create adapter. Error: %v", err)
+	}
+	defer raw.Destruct()
+
+	r, err = raw.Query(context.Background(), q, nil)
+	if err != nil {
+		t.Fatalf("Error selecting: %v", err)
+	}
+
+	need = "int64, []uint8, []uint8"
+	got = fmt.Sprintf("%T, %T, %T", r[0]["id"], r[0]["name"], r[0]["created"])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestQueryCancel tests that a query is aborted when the context is cancelled.
+func TestQueryCancel(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	q := `select sleep(5)`
+
+	start := time.Now()
+
+	_, err := adapter.Query(ctx, q, nil)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := 2 * time.Second
+	got := time.Since(start)
+	if got > need {
+		t.Errorf("Need query to be aborted within %v, took %v", need, got)
+	}
+}
+
 // TestSelectBulk tests bulk select query.
 func TestSelectBulk(t *testing.T) {
 	clearTestTable(t)
@@ -289,6 +615,12 @@ func TestInsertBulk(t *testing.T) {
 		t.Errorf("Last insert id: need `%d`, got `%d`", need, got)
 	}
 
+	idsNeed := "[1 2]"
+	idsGot := fmt.Sprintf("%v", r[0][internal.LastInsertIDs].([]int64))
+	if idsGot != idsNeed {
+		t.Errorf("Last insert ids: need `%s`, got `%s`", idsNeed, idsGot)
+	}
+
 	// check whether all data is inserted
 	cr, _ := adapter.Query(context.Background(), `select * from sample`, nil)
 	if len(cr) == 0 {
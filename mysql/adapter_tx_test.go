@@ -2,8 +2,12 @@ package mysql_test
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/kosatnkn/db"
 	"github.com/kosatnkn/db/internal"
 )
 
@@ -387,7 +391,8 @@ func TestNestedTxInnerFail(t *testing.T) {
 		t.Fatal("Result type mismatch")
 	}
 
-	need = 0
+	// the outer operation recovers from the failure of the inner operation
+	need = 1
 	got = int(result[0]["count"].(int64))
 	if got != need {
 		t.Errorf("Need %d, got %d", need, got)
@@ -462,3 +467,202 @@ func TestNestedTxOuterFail(t *testing.T) {
 		t.Errorf("Need %d, got %d", need, got)
 	}
 }
+
+// TestNestedTxOuterFailAfterInnerSuccess tests for rolling back of a successful inner operation
+// when the outer operation fails afterwards.
+func TestNestedTxOuterFailAfterInnerSuccess(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	ctx := context.Background()
+
+	q1 := `insert into sample(name, password) values ('Success Data 1', 'pwd1')`
+	q2 := `insert into sample(name, password) values ('Success Data 2', 'pwd2')`
+
+	errOuter := errors.New("outer operation failed")
+
+	// run q1
+	_, err := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+		_, err1 := adapter.Query(ctx, q1, nil)
+		if err1 != nil {
+			return nil, err1
+		}
+
+		// run q2
+		_, err2 := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+			return adapter.Query(ctx, q2, nil)
+		})
+		if err2 != nil {
+			t.Errorf("Error running query 2: %s", err2.Error())
+		}
+
+		// HERE: fail after the inner operation has succeeded
+		return nil, errOuter
+	})
+	if err != errOuter {
+		t.Errorf("Need %v, got %v", errOuter, err)
+	}
+
+	// check whether all data is rolled back
+	r, err := adapter.Query(context.Background(), `select count(*) as count from sample`, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	need := 0
+	got := int(r[0]["count"].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+}
+
+// TestTxReadOnly tests for the failure of writing in a read only transaction.
+func TestTxReadOnly(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	opts := &db.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	}
+
+	q := `insert into sample(name, password) values ('Success Data 1', 'pwd1')`
+
+	_, err := adapter.WrapInTxWithOptions(context.Background(), opts, func(ctx context.Context) (interface{}, error) {
+		return adapter.Query(ctx, q, nil)
+	})
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	errNeed := "Error 1792"
+	errGot := err.Error()[:10]
+	if errNeed != errGot {
+		t.Errorf("Need %s, got %s", errNeed, errGot)
+	}
+}
+
+// TestNestedTxStricterIsolation tests for the failure of a nested transaction requesting a stricter isolation level,
+// or a read only transaction inside one that is not.
+func TestNestedTxStricterIsolation(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	tests := []struct {
+		outer *db.TxOptions
+		inner *db.TxOptions
+		need  string
+	}{
+		{
+			outer: &db.TxOptions{Isolation: sql.LevelRepeatableRead},
+			inner: &db.TxOptions{Isolation: sql.LevelSerializable},
+			need:  "mysql-adapter: cannot start a Serializable transaction inside a Repeatable Read transaction",
+		},
+		{
+			outer: nil,
+			inner: &db.TxOptions{ReadOnly: true},
+			need:  "mysql-adapter: cannot start a read only transaction inside a transaction that is not read only",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := adapter.WrapInTxWithOptions(context.Background(), test.outer, func(ctx context.Context) (interface{}, error) {
+			return adapter.WrapInTxWithOptions(ctx, test.inner, func(ctx context.Context) (interface{}, error) {
+				return nil, nil
+			})
+		})
+		if err == nil {
+			t.Errorf("Need error `%s`, got nil", test.need)
+			continue
+		}
+
+		got := err.Error()
+		if test.need != got {
+			t.Errorf("Need %s, got %s", test.need, got)
+		}
+	}
+}
+
+// TestTxRetry tests retrying of a transaction that fails due to a deadlock inside a nested transaction.
+//
+// A deadlock rolls back the whole transaction along with the savepoint of the nested transaction,
+// so rolling back to the savepoint fails as well.
+func TestTxRetry(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	other := newDBAdapter(t)
+	defer other.Destruct()
+
+	_, err := adapter.QueryBulk(context.Background(), `insert into sample(name, password) values (?name, ?password)`, newBatchParams(10))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	r, _ := adapter.Query(context.Background(), `select id from sample order by id`, nil)
+	first := map[string]interface{}{"id": r[0]["id"]}
+	second := map[string]interface{}{"id": r[1]["id"]}
+
+	qUpdate := `update sample set password = 'changed' where id = ?id`
+	qUpdateRest := `update sample set password = 'changed' where id >= ?id`
+
+	lockedFirst := make(chan struct{})
+	lockedRest := make(chan struct{})
+	done := make(chan error)
+
+	// lock the rows in the opposite order using another connection,
+	// changing more rows so that the server picks the retried transaction to roll back
+	go func() {
+		_, err := other.WrapInTx(context.Background(), func(ctx context.Context) (interface{}, error) {
+			<-lockedFirst
+
+			if _, err := other.Query(ctx, qUpdateRest, second); err != nil {
+				return nil, err
+			}
+			close(lockedRest)
+
+			return other.Query(ctx, qUpdate, first)
+		})
+		done <- err
+	}()
+
+	policy := db.RetryPolicy{MaxAttempts: 3}
+
+	var attempts []int
+
+	_, err = adapter.WrapInTxWithRetry(context.Background(), nil, policy, func(ctx context.Context) (interface{}, error) {
+		attempts = append(attempts, db.TxAttempt(ctx))
+
+		return adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+			if _, err := adapter.Query(ctx, qUpdate, first); err != nil {
+				return nil, err
+			}
+
+			if db.TxAttempt(ctx) == 1 {
+				close(lockedFirst)
+				<-lockedRest
+			}
+
+			return adapter.Query(ctx, qUpdate, second)
+		})
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("Error running conflicting transaction: %v", err)
+	}
+
+	need := "[1 2]"
+	got := fmt.Sprintf("%v", attempts)
+	if need != got {
+		t.Errorf("Attempts: need %s, got %s", need, got)
+	}
+}
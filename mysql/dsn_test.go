@@ -0,0 +1,47 @@
+package mysql
+
+import (
+	"crypto/tls"
+	"fmt"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// TestDSN tests that the settings reach the driver as they are.
+func TestDSN(t *testing.T) {
+	cfg := Config{
+		Host:     "127.0.0.1",
+		Port:     3306,
+		Database: "sample",
+		User:     "root",
+		Password: `p@ss/w:rd?& 'x`,
+		PoolSize: 10,
+		Params:   map[string]string{"charset": "utf8mb4", "sql_mode": "'TRADITIONAL,ANSI_QUOTES'", "time_zone": "'+05:30'"},
+	}
+
+	// the driver only accepts the names of registered TLS configurations
+	mysqldriver.RegisterTLSConfig("custom", &tls.Config{})
+	defer mysqldriver.DeregisterTLSConfig("custom")
+
+	got, err := mysqldriver.ParseDSN(cfg.withDefaults().dsn("custom"))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if got.Passwd != cfg.Password {
+		t.Errorf("Password: need `%s`, got `%s`", cfg.Password, got.Passwd)
+	}
+
+	need := "root 127.0.0.1:3306 sample true 10s custom"
+	gotCfg := fmt.Sprintf("%s %s %s %t %s %s", got.User, got.Addr, got.DBName, got.ParseTime, got.Timeout, got.TLSConfig)
+	if gotCfg != need {
+		t.Errorf("Need `%s`, got `%s`", need, gotCfg)
+	}
+
+	pNeed := "map[charset:utf8mb4 sql_mode:'TRADITIONAL,ANSI_QUOTES' time_zone:'+05:30']"
+	pGot := fmt.Sprintf("%v", got.Params)
+	if pGot != pNeed {
+		t.Errorf("Params: need `%s`, got `%s`", pNeed, pGot)
+	}
+}
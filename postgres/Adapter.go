@@ -3,43 +3,52 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 
 	// database driver for postgres
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	"github.com/kosatnkn/db"
 	"github.com/kosatnkn/db/internal"
 )
 
+// maxParameters is the maximum number of bind parameters allowed in a statement.
+const maxParameters = 65535
+
 // Adapter is used to communicate with a Postgres database.
 type Adapter struct {
-	cfg      Config
-	pool     *sql.DB
-	pqPrefix string
+	cfg  Config
+	pool *sql.DB
 }
 
 // NewAdapter creates a new Postgres adapter instance.
 func NewAdapter(cfg Config) (db.AdapterInterface, error) {
-	connString := fmt.Sprintf("user=%s password=%s dbname=%s host=%s port=%d sslmode=disable",
-		cfg.User, cfg.Password, cfg.Database, cfg.Host, cfg.Port)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	cfg = cfg.withDefaults()
 
-	db, err := sql.Open("postgres", connString)
+	// the connector parses the connection string right away, so invalid params are reported here
+	connector, err := pq.NewConnector(cfg.dsn())
 	if err != nil {
-		return nil, err
+		// errors in the connection string may quote the password
+		return nil, internal.RedactError(fmt.Errorf("postgres-adapter: %v", err), cfg.Password)
 	}
 
+	db := sql.OpenDB(connector)
+
 	// pool configurations
 	db.SetMaxOpenConns(cfg.PoolSize)
-	//db.SetMaxIdleConns(2)
-	//db.SetConnMaxLifetime(time.Hour)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	a := &Adapter{
-		cfg:      cfg,
-		pool:     db,
-		pqPrefix: "?",
+		cfg:  cfg,
+		pool: db,
 	}
 
 	// check whether the db is accessible
@@ -59,8 +68,13 @@ func (a *Adapter) Ping() error {
 //
 // Note: For INSERT statements postgres does not return the insert id by default.
 // The returning identifier should be defined in the query using the RETURNING clause.
+//
+// A statement having a RETURNING clause returns all the rows produced by it, like a select would.
+// To stay compatible with plain inserts each of these rows also has the affected rows,
+// and the value of the first returned column of the last row as the last insert id.
+// When no rows are returned the result has a single row with 0 affected rows and a nil last insert id.
 func (a *Adapter) Query(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
-	convertedQuery, placeholders := a.convertQuery(query)
+	convertedQuery, placeholders := a.convertQuery(query, params)
 
 	reorderedParams, err := a.reorderParameters(params, placeholders)
 	if err != nil {
@@ -73,24 +87,29 @@ func (a *Adapter) Query(ctx context.Context, query string, params map[string]int
 	}
 	defer stmt.Close()
 
-	// check whether the query is a select statement
-	if a.isSelect(convertedQuery) {
-		rows, err := stmt.Query(reorderedParams...)
+	st := a.statement(convertedQuery)
+
+	// check whether the query has a RETURNING clause
+	if st.Returning {
+		rows, err := stmt.QueryContext(ctx, reorderedParams...)
 		if err != nil {
 			return nil, err
 		}
 
-		return a.prepareDataSet(rows)
+		return a.prepareReturningSet(rows)
 	}
 
-	// check whether the query is an insert statement
-	if a.isInsert(convertedQuery) {
-		row := stmt.QueryRow(reorderedParams...)
-		return a.prepareInsertResultSet(row)
+	// check whether the query returns rows
+	if st.ReturnsRows() {
+		rows, err := stmt.QueryContext(ctx, reorderedParams...)
+		if err != nil {
+			return nil, err
+		}
+
+		return a.prepareDataSet(rows)
 	}
 
-	result, err := stmt.Exec(reorderedParams...)
-	// result, err := stmt.Query(reorderedParams...)
+	result, err := stmt.ExecContext(ctx, reorderedParams...)
 	if err != nil {
 		return nil, err
 	}
@@ -98,53 +117,165 @@ func (a *Adapter) Query(ctx context.Context, query string, params map[string]int
 	return a.prepareResultSet(result)
 }
 
+// QueryEach runs a query and calls fn with each row of the result one at a time,
+// without holding the whole result in memory.
+//
+// Iteration stops at the first error returned by fn and that error is returned.
+// Inside a transaction fn should not run other queries, since the connection is busy until all rows are read.
+func (a *Adapter) QueryEach(ctx context.Context, query string, params map[string]interface{}, fn func(row map[string]interface{}) error) error {
+	convertedQuery, placeholders := a.convertQuery(query, params)
+
+	reorderedParams, err := a.reorderParameters(params, placeholders)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := a.prepareStatement(ctx, convertedQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, reorderedParams...)
+	if err != nil {
+		return err
+	}
+
+	return a.scanRows(rows, fn)
+}
+
 // QueryBulk runs a query using an array of parameters and return the combined result.
 //
 // This query is intended to do bulk INSERTS, UPDATES and DELETES.
 // Using this for SELECTS will result in an error.
+//
+// For statements having a RETURNING clause the value of the first returned column is returned as last_insert_ids,
+// holding one entry for each set of parameters in the same order. The entry is the value of the last returned row
+// when a set of parameters returns many rows, and nil when it returns none as with ON CONFLICT DO NOTHING.
 func (a *Adapter) QueryBulk(ctx context.Context, query string, params []map[string]interface{}) ([]map[string]interface{}, error) {
-	convertedQuery, placeholders := a.convertQuery(query)
+	convertedQuery, _ := a.convertQuery(query, nil)
+	st := a.statement(convertedQuery)
 
 	// check whether the query is a select statement
-	if a.isSelect(convertedQuery) {
+	if st.Reads() {
 		return nil, fmt.Errorf("postgres-adapter: select queries are not allowed. use Query() instead")
 	}
 
-	stmt, err := a.prepareStatement(ctx, convertedQuery)
-	if err != nil {
-		return nil, err
-	}
-	defer stmt.Close()
+	// slice parameters can change the converted query from one set of parameters to the next,
+	// so statements are prepared as and when they are needed
+	stmts := internal.NewStmtCache(func(q string) (*sql.Stmt, error) {
+		return a.prepareStatement(ctx, q)
+	})
+	defer stmts.Close()
 
 	var lastID interface{}
 	var affRows int64
+	lastIDs := make([]interface{}, 0)
+
+	for _, pms := range params {
+		convertedQuery, placeholders := a.convertQuery(query, pms)
+
+		reorderedParams, err := a.reorderParameters(pms, placeholders)
+		if err != nil {
+			return nil, err
+		}
+
+		stmt, err := stmts.Get(convertedQuery)
+		if err != nil {
+			return nil, err
+		}
 
-	if a.isInsert(convertedQuery) {
-		for _, pms := range params {
-			reorderedParams, err := a.reorderParameters(pms, placeholders)
+		if st.Returning {
+			ids, err := a.queryReturnedIDs(ctx, stmt, reorderedParams)
 			if err != nil {
 				return nil, err
 			}
 
-			row := stmt.QueryRow(reorderedParams...)
-			if err := row.Err(); err != nil {
-				return nil, err
+			// keep one id for each set of parameters, so that they can be matched by position
+			var id interface{}
+			if len(ids) > 0 {
+				id = ids[len(ids)-1]
+				lastID = id
 			}
+			lastIDs = append(lastIDs, id)
+			affRows += int64(len(ids))
 
-			row.Scan(&lastID)
-			affRows++
+			continue
 		}
 
-		return a.formatResultSet(lastID, affRows), nil
+		result, err := stmt.ExecContext(ctx, reorderedParams...)
+		if err != nil {
+			return nil, err
+		}
+
+		ar, _ := result.RowsAffected()
+		affRows += ar
 	}
 
-	for _, pms := range params {
-		reorderedParams, err := a.reorderParameters(pms, placeholders)
+	return a.formatBulkResultSet(lastID, lastIDs, affRows), nil
+}
+
+// InsertBatch runs a single row insert query for an array of parameters and return the combined result.
+//
+// The row of values of the query is repeated for as many sets of parameters as fit in to a single statement
+// of at most 65535 bind parameters.
+//
+// When the query has a RETURNING clause the value of the first returned column of each row is returned
+// in insertion order as last_insert_ids. Unlike QueryBulk() rows skipped by ON CONFLICT DO NOTHING are left out,
+// since a multi row statement does not tell which rows they are, so the ids may not match params by position.
+func (a *Adapter) InsertBatch(ctx context.Context, query string, params []map[string]interface{}) ([]map[string]interface{}, error) {
+	bq, err := internal.ParseBatchQuery(query, internal.Postgres)
+	if err != nil {
+		return nil, fmt.Errorf("postgres-adapter: %v", err)
+	}
+
+	st := a.statement(query)
+
+	// all chunks but the last have the same number of rows, so they share a statement
+	stmts := internal.NewStmtCache(func(q string) (*sql.Stmt, error) {
+		return a.prepareStatement(ctx, q)
+	})
+	defer stmts.Close()
+
+	var lastID interface{}
+	var affRows int64
+	lastIDs := make([]interface{}, 0, len(params))
+
+	for _, rows := range bq.Split(params, maxParameters, 0) {
+		batchQuery, batchParams, err := bq.Build(rows)
+		if err != nil {
+			return nil, fmt.Errorf("postgres-adapter: %v", err)
+		}
+
+		convertedQuery, placeholders := a.convertQuery(batchQuery, batchParams)
+
+		reorderedParams, err := a.reorderParameters(batchParams, placeholders)
 		if err != nil {
 			return nil, err
 		}
 
-		result, err := stmt.Exec(reorderedParams...)
+		stmt, err := stmts.Get(convertedQuery)
+		if err != nil {
+			return nil, err
+		}
+
+		if st.Returning {
+			ids, err := a.queryReturnedIDs(ctx, stmt, reorderedParams)
+			if err != nil {
+				return nil, err
+			}
+
+			// a chunk inserts many rows, each returning an id of its own
+			if len(ids) > 0 {
+				lastID = ids[len(ids)-1]
+			}
+			lastIDs = append(lastIDs, ids...)
+			affRows += int64(len(ids))
+
+			continue
+		}
+
+		result, err := stmt.ExecContext(ctx, reorderedParams...)
 		if err != nil {
 			return nil, err
 		}
@@ -153,81 +284,184 @@ func (a *Adapter) QueryBulk(ctx context.Context, query string, params []map[stri
 		affRows += ar
 	}
 
-	return a.formatResultSet(lastID, affRows), nil
+	return a.formatBulkResultSet(lastID, lastIDs, affRows), nil
+}
+
+// CopyFrom loads rows in to the columns of table using COPY FROM, which is the fastest way to load many rows.
+//
+// The table can be qualified by a schema as in schema.table. Each row should have a value for each of the columns.
+// COPY runs in the transaction attached to the context, or in a transaction of its own when there is none.
+//
+// The result has the number of loaded rows as the affected rows.
+func (a *Adapter) CopyFrom(ctx context.Context, table string, columns []string, rows [][]interface{}) ([]map[string]interface{}, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("postgres-adapter: no columns to copy in to '%s'", table)
+	}
+
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("postgres-adapter: row %d has %d values, need %d", i, len(row), len(columns))
+		}
+	}
+
+	// COPY is only allowed inside a transaction
+	if _, ok := ctx.Value(internal.TxKey).(*internal.Tx); !ok {
+		res, err := a.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+			return a.CopyFrom(ctx, table, columns, rows)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return res.([]map[string]interface{}), nil
+	}
+
+	stmt, err := a.prepareStatement(ctx, a.copyQuery(table, columns))
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return nil, err
+		}
+	}
+
+	// flush the buffered rows and end the copy
+	result, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.prepareResultSet(result)
 }
 
 // WrapInTx runs the content of the function in a single transaction.
+//
+// Nested calls run inside the transaction of the outermost call using savepoints.
+// A failing nested call only rolls back the work done inside it,
+// so that the outer function can recover from the failure.
+//
+// Only the outermost call commits the transaction. An error committing or rolling back is returned as a db.TxError.
 func (a *Adapter) WrapInTx(ctx context.Context, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	return a.WrapInTxWithOptions(ctx, nil, fn)
+}
+
+// WrapInTxWithOptions runs the content of the function in a single transaction started using opts.
+//
+// The default options of the database are used when opts is nil.
+// A nested call requesting a stricter isolation level than the enclosing transaction,
+// or a read only transaction inside one that is not, results in an error.
+func (a *Adapter) WrapInTxWithOptions(ctx context.Context, opts *db.TxOptions, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
 	// attach a transaction to context
-	ctx, err := a.attachTx(ctx)
+	ctx, tx, err := a.attachTx(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	// get a reference to the attached transaction
-	tx := ctx.Value(internal.TxKey).(*sql.Tx)
-
 	// run function
 	res, err := fn(ctx)
 
 	// decide whether to commit or rollback
 	//
-	// Here we deliberately avoid catching errors from Commit() and Rollback().
-	// This is because the sql package does not give a method to check whether
-	// a transaction has already completed or not.
-	//
-	// When executing nested operations in a single transaction, either the leaf operation or the
-	// earliest failing operation of the operation tree will close the transaction.
-	// Since all operations prior to that operation also tries to close the transaction
-	// it will always result in an error.
-	// If we catch errors from Commit() and Rollback(), nested transactions
-	// will always fail because of this.
+	// Failing to finish the transaction is reported as a db.TxError,
+	// which also carries the error returned by the function if there was one.
 	if err != nil {
-		tx.Rollback()
+		if txErr := tx.Rollback(); txErr != nil {
+			return nil, &db.TxError{Err: err, TxErr: txErr}
+		}
+
 		return nil, err
 	}
 
-	tx.Commit()
+	if txErr := tx.Commit(ctx); txErr != nil {
+		return nil, &db.TxError{TxErr: txErr}
+	}
 
 	return res, nil
 }
 
+// WrapInTxWithRetry runs the content of the function in a single transaction started using opts,
+// running it again when the transaction fails due to a serialization failure or a deadlock.
+//
+// Only the outermost transaction is retried, since a failure at any level rolls back the whole transaction.
+// A nested call behaves the same as WrapInTxWithOptions().
+// Use db.TxAttempt() to get the attempt number inside the function.
+func (a *Adapter) WrapInTxWithRetry(ctx context.Context, opts *db.TxOptions, policy db.RetryPolicy, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if _, ok := ctx.Value(internal.TxKey).(*internal.Tx); ok {
+		return a.WrapInTxWithOptions(ctx, opts, fn)
+	}
+
+	return internal.Retry(ctx, internal.RetryPolicy(policy), a.isRetryable, func(ctx context.Context) (interface{}, error) {
+		return a.WrapInTxWithOptions(ctx, opts, fn)
+	})
+}
+
 // Destruct will close the Postgres adapter releasing all resources.
 func (a *Adapter) Destruct() error {
 	return a.pool.Close()
 }
 
-// isSelect checks whether q is a select query.
-func (a *Adapter) isSelect(q string) bool {
-	return strings.ToLower(q[:6]) == "select"
+// statement finds the kind of the query q.
+func (a *Adapter) statement(q string) internal.Statement {
+	return internal.Classify(q, internal.Postgres)
 }
 
-// isInsert checks whether q is an insert query.
-func (a *Adapter) isInsert(q string) bool {
-	return strings.ToLower(q[:6]) == "insert"
+// isRetryable checks whether err is caused by a serialization failure (40001) or a deadlock (40P01).
+func (a *Adapter) isRetryable(err error) bool {
+	// A failure that rolls back the whole transaction also drops its savepoints, so rolling back
+	// the savepoint of a nested call fails as well. errors.As() only looks at that rollback error
+	// of a db.TxError, so the error returned by the wrapped function is checked separately.
+	var txErr *db.TxError
+	if errors.As(err, &txErr) && txErr.Err != nil && a.isRetryable(txErr.Err) {
+		return true
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
 }
 
 // attachTx attaches a database transaction to the context.
 //
+// The transaction is bound to the context, so that it is rolled back when the context is cancelled.
+//
 // This will first check to see whether there is a transaction already in the context.
 // Having a transaction already attached to context probably means that the calling function
 // has been wrapped in a transaction in a previous stage.
-// When this is the case use the existing attached transaction.
-// Otherwise create a new transaction and attach.
-func (a *Adapter) attachTx(ctx context.Context) (context.Context, error) {
-	// check tx altready exists
-	tx := ctx.Value(internal.TxKey)
-	if tx != nil {
-		return ctx, nil
+// When this is the case a savepoint is created in the existing attached transaction.
+// Otherwise create a new transaction using opts and attach.
+func (a *Adapter) attachTx(ctx context.Context, opts *db.TxOptions) (context.Context, *internal.Tx, error) {
+	var o internal.TxOptions
+	if opts != nil {
+		o = internal.TxOptions(*opts)
 	}
 
-	// attach new tx
-	tx, err := a.pool.Begin()
-	if err != nil {
-		return nil, err
+	if err := internal.CheckNestedTx(ctx, o); err != nil {
+		return nil, nil, fmt.Errorf("postgres-adapter: %v", err)
 	}
 
-	return context.WithValue(ctx, internal.TxKey, tx), nil
+	return internal.AttachTx(ctx, o, func(ctx context.Context) (*sql.Tx, error) {
+		tx, err := a.pool.BeginTx(ctx, &sql.TxOptions{Isolation: o.Isolation, ReadOnly: o.ReadOnly})
+		if err != nil {
+			return nil, err
+		}
+
+		// the driver does not support deferrable transactions,
+		// so it is set before anything else runs in the transaction
+		if o.Deferrable {
+			if _, err := tx.ExecContext(ctx, "SET TRANSACTION DEFERRABLE"); err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+
+		return tx, nil
+	})
 }
 
 // convertQuery converts the named parameter query to a placeholder query that Postgres library understands.
@@ -239,30 +473,63 @@ func (a *Adapter) attachTx(ctx context.Context) (context.Context, error) {
 // UPDATE tbl SET col1 = $1, col2 = $2 WHERE col3 = $3
 // DELETE FROM tbl WHERE col = $1
 //
-// This will return the query and a slice of strings containing named parameter name in the order that they are found
-// in the query.
-func (a *Adapter) convertQuery(query string) (string, []string) {
+// A named parameter that is used more than once is mapped to the same placeholder,
+// so that its value is bound only once.
+//
+// SELECT * FROM tbl WHERE col1 = ?val OR col2 = ?val -> SELECT * FROM tbl WHERE col1 = $1 OR col2 = $1
+//
+// A named parameter having a slice value in params is expanded to a placeholder for each element.
+//
+// SELECT * FROM tbl WHERE col IN (?vals) -> SELECT * FROM tbl WHERE col IN ($1, $2, $3)
+//
+// This will return the query and a slice of strings containing distinct named parameter names in the order that they
+// are first found in the query. Named parameters inside string literals, quoted identifiers and comments are left as
+// they are.
+func (a *Adapter) convertQuery(query string, params map[string]interface{}) (string, []string) {
 	query = strings.TrimSpace(query)
-	exp := regexp.MustCompile(`\` + a.pqPrefix + `\w+`)
 
-	namedParams := exp.FindAllString(query, -1)
+	var namedParams []string
+	placeholders := make(map[string]string)
+	pos := 1
 
-	for i := 0; i < len(namedParams); i++ {
-		namedParams[i] = strings.TrimPrefix(namedParams[i], a.pqPrefix)
-	}
+	query = internal.ReplaceParams(query, internal.Postgres, func(name string) string {
+		ph, ok := placeholders[name]
+		if !ok {
+			var n int
+			ph, n = a.placeholders(params[name], pos)
+			pos += n
 
-	paramPosition := 0
-	query = string(exp.ReplaceAllFunc([]byte(query), func(param []byte) []byte {
-		paramPosition++
-		paramName := fmt.Sprintf("$%d", paramPosition)
+			namedParams = append(namedParams, name)
+			placeholders[name] = ph
+		}
 
-		return []byte(paramName)
-	}))
+		return ph
+	})
 
 	return query, namedParams
 }
 
+// placeholders returns the placeholders needed to bind the value starting from position pos
+// along with the number of positions used.
+//
+// A slice value needs a placeholder for each of its elements.
+func (a *Adapter) placeholders(value interface{}, pos int) (string, int) {
+	n := 1
+	if elems, ok := internal.ExpandSlice(value); ok {
+		n = len(elems)
+	}
+
+	phs := make([]string, n)
+	for i := range phs {
+		phs[i] = fmt.Sprintf("$%d", pos+i)
+	}
+
+	return strings.Join(phs, ", "), n
+}
+
 // reorderParameters reorders the parameters map in the order of named parameters slice.
+//
+// Slice parameters are expanded in to their elements to match the placeholders created by convertQuery().
 func (a *Adapter) reorderParameters(params map[string]interface{}, namedParams []string) ([]interface{}, error) {
 	var reorderedParams []interface{}
 
@@ -273,32 +540,66 @@ func (a *Adapter) reorderParameters(params map[string]interface{}, namedParams [
 			return nil, fmt.Errorf("postgres-adapter: parameter '%s' is missing", param)
 		}
 
+		// expand slices in to individual parameters
+		if elems, ok := internal.ExpandSlice(paramValue); ok {
+			if len(elems) == 0 {
+				return nil, fmt.Errorf("postgres-adapter: parameter '%s' is an empty slice", param)
+			}
+
+			reorderedParams = append(reorderedParams, elems...)
+			continue
+		}
+
 		reorderedParams = append(reorderedParams, paramValue)
 	}
 
 	return reorderedParams, nil
 }
 
+// copyQuery creates the COPY FROM statement for the columns of table, which may be qualified by a schema.
+func (a *Adapter) copyQuery(table string, columns []string) string {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return pq.CopyInSchema(schema, name, columns...)
+	}
+
+	return pq.CopyIn(table, columns...)
+}
+
 // prepareStatement creates a prepared statement using the query.
 //
 // Checks whether there is a transaction attached to the context.
 // If so use that transaction to prepare statement else use the pool.
 func (a *Adapter) prepareStatement(ctx context.Context, query string) (*sql.Stmt, error) {
-	tx := ctx.Value(internal.TxKey)
-	if tx != nil {
-		return tx.(*sql.Tx).Prepare(query)
+	if tx, ok := ctx.Value(internal.TxKey).(*internal.Tx); ok {
+		return tx.Tx.PrepareContext(ctx, query)
 	}
 
-	return a.pool.Prepare(query)
+	return a.pool.PrepareContext(ctx, query)
 }
 
 // prepareDataSet creates a dataset using the output of a SELECT statement.
+func (a *Adapter) prepareDataSet(rows *sql.Rows) ([]map[string]interface{}, error) {
+	var data []map[string]interface{}
+
+	err := a.scanRows(rows, func(row map[string]interface{}) error {
+		data = append(data, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// scanRows calls fn with each row of the output of a SELECT statement, stopping at the first error returned by fn.
+//
+// Each row is a new map, so fn can keep it.
 //
 // Source: https://kylewbanks.com/blog/query-result-to-map-in-golang
-func (a *Adapter) prepareDataSet(rows *sql.Rows) ([]map[string]interface{}, error) {
+func (a *Adapter) scanRows(rows *sql.Rows, fn func(row map[string]interface{}) error) error {
 	defer rows.Close()
 
-	var data []map[string]interface{}
 	cols, _ := rows.Columns()
 
 	// create a slice of interface{}'s to represent each column
@@ -314,7 +615,7 @@ func (a *Adapter) prepareDataSet(rows *sql.Rows) ([]map[string]interface{}, erro
 		// scan the result into the column pointers
 		err := rows.Scan(columnPointers...)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		// create our map, and retrieve the value for each column from the pointers slice
@@ -326,22 +627,77 @@ func (a *Adapter) prepareDataSet(rows *sql.Rows) ([]map[string]interface{}, erro
 			row[colName] = *val
 		}
 
-		data = append(data, row)
+		if err := fn(row); err != nil {
+			return err
+		}
 	}
 
-	return data, nil
+	// iteration stops early when an error occurs, as in the case of a cancelled context
+	return rows.Err()
 }
 
-// prepareInsertResultSet creates a resultset using the result of QueryRow().
-func (a *Adapter) prepareInsertResultSet(row *sql.Row) ([]map[string]interface{}, error) {
-	if err := row.Err(); err != nil {
+// queryReturnedIDs runs a statement having a RETURNING clause and returns the value of the first returned column
+// of each row.
+func (a *Adapter) queryReturnedIDs(ctx context.Context, stmt *sql.Stmt, params []interface{}) ([]interface{}, error) {
+	rows, err := stmt.QueryContext(ctx, params...)
+	if err != nil {
 		return nil, err
 	}
 
-	var id interface{}
-	row.Scan(&id)
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
 
-	return a.formatResultSet(id, 1), nil
+	data, err := a.prepareDataSet(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]interface{}, len(data))
+	for i, row := range data {
+		ids[i] = row[cols[0]]
+	}
+
+	return ids, nil
+}
+
+// prepareReturningSet creates a dataset using the output of a statement having a RETURNING clause.
+//
+// Each row also gets the number of returned rows as the affected rows
+// and the value of the first column of the last row as the last insert id,
+// unless the statement returns columns by those names.
+// When no rows are returned the result is the same as that of a statement without a RETURNING clause.
+func (a *Adapter) prepareReturningSet(rows *sql.Rows) ([]map[string]interface{}, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+
+	data, err := a.prepareDataSet(rows)
+	if err != nil {
+		return nil, err
+	}
+	// a statement that changed no rows still reports that it did not
+	if len(data) == 0 {
+		return a.formatResultSet(nil, 0), nil
+	}
+
+	aff := int64(len(data))
+	id := data[len(data)-1][cols[0]]
+
+	for _, row := range data {
+		if _, ok := row[internal.AffectedRows]; !ok {
+			row[internal.AffectedRows] = aff
+		}
+		if _, ok := row[internal.LastInsertID]; !ok {
+			row[internal.LastInsertID] = id
+		}
+	}
+
+	return data, nil
 }
 
 // prepareResultSet creates a resultset using the result of Exec().
@@ -367,3 +723,12 @@ func (a *Adapter) formatResultSet(id interface{}, aff int64) []map[string]interf
 		internal.LastInsertID: id,
 	})
 }
+
+// formatBulkResultSet creates a resultset using last insert id, the insert ids of all sets of parameters
+// and affected rows.
+func (a *Adapter) formatBulkResultSet(id interface{}, ids []interface{}, aff int64) []map[string]interface{} {
+	data := a.formatResultSet(id, aff)
+	data[0][internal.LastInsertIDs] = ids
+
+	return data
+}
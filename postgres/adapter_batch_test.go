@@ -0,0 +1,264 @@
+package postgres_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/kosatnkn/db"
+	"github.com/kosatnkn/db/internal"
+)
+
+// benchRows is the number of rows inserted by each iteration of the benchmarks.
+const benchRows = 1000
+
+// newBatchParams creates n sets of parameters for the test table.
+func newBatchParams(n int) []map[string]interface{} {
+	params := make([]map[string]interface{}, n)
+	for i := range params {
+		params[i] = map[string]interface{}{
+			"name":     fmt.Sprintf("Name %d", i+1),
+			"password": fmt.Sprintf("pwd%d", i+1),
+		}
+	}
+
+	return params
+}
+
+// TestInsertBatch tests inserting many rows using multi row inserts.
+func TestInsertBatch(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	batch, ok := adapter.(db.BatchInsertInterface)
+	if !ok {
+		t.Fatal("Need adapter to implement db.BatchInsertInterface")
+	}
+
+	q := `insert into sample.sample(name, password) values (?name, ?password) returning id`
+
+	r, err := batch.InsertBatch(context.Background(), q, newBatchParams(3))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := 3
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	need = 3
+	got = int(r[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Last insert id: need `%d`, got `%d`", need, got)
+	}
+
+	idsNeed := "[1 2 3]"
+	idsGot := fmt.Sprintf("%v", r[0][internal.LastInsertIDs].([]interface{}))
+	if idsGot != idsNeed {
+		t.Errorf("Last insert ids: need `%s`, got `%s`", idsNeed, idsGot)
+	}
+
+	// check whether all data is inserted
+	cr, _ := adapter.Query(context.Background(), `select * from sample.sample order by id`, nil)
+	if len(cr) != 3 {
+		t.Fatalf("Need 3 records, got %d records", len(cr))
+	}
+
+	cNeed := "3, Name 3, pwd3"
+	cGot := fmt.Sprintf("%d, %s, %s", int(cr[2]["id"].(int64)), cr[2]["name"], cr[2]["password"])
+	if cGot != cNeed {
+		t.Errorf("Need `%s`, got `%s`", cNeed, cGot)
+	}
+}
+
+// TestInsertBatchChunks tests inserting more rows than the number of bind parameters allowed in a statement.
+func TestInsertBatchChunks(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q := `insert into sample.sample(name, password) values (?name, ?password) returning id`
+
+	r, err := adapter.(db.BatchInsertInterface).InsertBatch(context.Background(), q, newBatchParams(40000))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := 40000
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	got = len(r[0][internal.LastInsertIDs].([]interface{}))
+	if got != need {
+		t.Errorf("Last insert ids: need `%d`, got `%d`", need, got)
+	}
+}
+
+// TestInsertBatchMissingParameter tests inserting rows without all named parameters.
+func TestInsertBatchMissingParameter(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q := `insert into sample.sample(name, password) values (?name, ?password)`
+	params := newBatchParams(2)
+	delete(params[1], "password")
+
+	_, err := adapter.(db.BatchInsertInterface).InsertBatch(context.Background(), q, params)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := "postgres-adapter: parameter 'password' is missing"
+	got := err.Error()
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// BenchmarkQueryBulk benchmarks inserting rows one statement at a time.
+func BenchmarkQueryBulk(b *testing.B) {
+	adapter := newDBAdapter(b)
+	defer adapter.Destruct()
+
+	q := `insert into sample.sample(name, password) values (?name, ?password)`
+	params := newBatchParams(benchRows)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := adapter.QueryBulk(context.Background(), q, params); err != nil {
+			b.Fatalf("Error: %v", err)
+		}
+	}
+}
+
+// BenchmarkInsertBatch benchmarks inserting rows using multi row inserts.
+func BenchmarkInsertBatch(b *testing.B) {
+	adapter := newDBAdapter(b)
+	defer adapter.Destruct()
+
+	q := `insert into sample.sample(name, password) values (?name, ?password)`
+	params := newBatchParams(benchRows)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := adapter.(db.BatchInsertInterface).InsertBatch(context.Background(), q, params); err != nil {
+			b.Fatalf("Error: %v", err)
+		}
+	}
+}
+
+// newCopyRows creates n rows for the test table.
+func newCopyRows(n int) [][]interface{} {
+	rows := make([][]interface{}, n)
+	for i := range rows {
+		rows[i] = []interface{}{fmt.Sprintf("Name %d", i+1), fmt.Sprintf("pwd%d", i+1)}
+	}
+
+	return rows
+}
+
+// TestCopyFrom tests loading rows using COPY FROM.
+func TestCopyFrom(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	copier, ok := adapter.(db.CopyFromInterface)
+	if !ok {
+		t.Fatal("Need adapter to implement db.CopyFromInterface")
+	}
+
+	r, err := copier.CopyFrom(context.Background(), "sample.sample", []string{"name", "password"}, newCopyRows(3))
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := 3
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	// check whether all data is loaded
+	cr, _ := adapter.Query(context.Background(), `select * from sample.sample order by id`, nil)
+	if len(cr) != 3 {
+		t.Fatalf("Need 3 records, got %d records", len(cr))
+	}
+
+	cNeed := "3, Name 3, pwd3"
+	cGot := fmt.Sprintf("%d, %s, %s", int(cr[2]["id"].(int64)), cr[2]["name"], cr[2]["password"])
+	if cGot != cNeed {
+		t.Errorf("Need `%s`, got `%s`", cNeed, cGot)
+	}
+}
+
+// TestCopyFromTxRollback tests that rows loaded inside a failing transaction are rolled back.
+func TestCopyFromTxRollback(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	_, err := adapter.WrapInTx(context.Background(), func(ctx context.Context) (interface{}, error) {
+		_, err := adapter.(db.CopyFromInterface).CopyFrom(ctx, "sample.sample", []string{"name", "password"}, newCopyRows(3))
+		if err != nil {
+			return nil, err
+		}
+
+		return adapter.Query(ctx, `select * from sample.non_existant_table`, nil)
+	})
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	r, _ := adapter.Query(context.Background(), `select count(*) as count from sample.sample`, nil)
+
+	need := 0
+	got := int(r[0]["count"].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+}
+
+// TestCopyFromRowLength tests loading a row not having a value for each column.
+func TestCopyFromRowLength(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	rows := newCopyRows(2)
+	rows[1] = rows[1][:1]
+
+	_, err := adapter.(db.CopyFromInterface).CopyFrom(context.Background(), "sample.sample", []string{"name", "password"}, rows)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := "postgres-adapter: row 1 has 1 values, need 2"
+	got := err.Error()
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// BenchmarkCopyFrom benchmarks loading rows using COPY FROM.
+func BenchmarkCopyFrom(b *testing.B) {
+	adapter := newDBAdapter(b)
+	defer adapter.Destruct()
+
+	rows := newCopyRows(benchRows)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := adapter.(db.CopyFromInterface).CopyFrom(context.Background(), "sample.sample", []string{"name", "password"}, rows); err != nil {
+			b.Fatalf("Error: %v", err)
+		}
+	}
+}
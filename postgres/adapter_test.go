@@ -2,9 +2,12 @@ package postgres_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kosatnkn/db"
 	"github.com/kosatnkn/db/internal"
@@ -21,7 +24,7 @@ import (
 //
 
 // newDBAdapter creates a new db adapter pointing to the test db.
-func newDBAdapter(t *testing.T) db.AdapterInterface {
+func newDBAdapter(t testing.TB) db.AdapterInterface {
 	cfg := postgres.Config{
 		Host:     "localhost",
 		Port:     5432,
@@ -41,7 +44,7 @@ func newDBAdapter(t *testing.T) db.AdapterInterface {
 }
 
 // clearTestTable clears all data from the test table.
-func clearTestTable(t *testing.T) {
+func clearTestTable(t testing.TB) {
 	adapter := newDBAdapter(t)
 	defer adapter.Destruct()
 
@@ -246,6 +249,402 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+// TestSelectQuotedPlaceholder tests that named parameters inside strings and comments are not converted.
+func TestSelectQuotedPlaceholder(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample.sample(name, password) values ('?draft', ?password) returning id`
+	params := map[string]interface{}{
+		"password": "pwd1",
+	}
+
+	_, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// select
+	q = `select * from sample.sample where name = '?draft' -- and password = ?password`
+
+	r, err := adapter.Query(context.Background(), q, nil)
+	if err != nil {
+		t.Fatalf("Error selecting: %v", err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("Need 1 record, got %d records", len(r))
+	}
+
+	need := "?draft, pwd1"
+	got := fmt.Sprintf("%s, %s", r[0]["name"], r[0]["password"])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestSelectRepeatedParameter tests select query using the same named parameter more than once.
+func TestSelectRepeatedParameter(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample.sample(name, password) values (?name, ?name) returning id`
+	params := map[string]interface{}{
+		"name": "Name 1",
+	}
+
+	_, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// select
+	q = `select * from sample.sample where name = ?name and password = ?name and id = ?id`
+	params = map[string]interface{}{
+		"name": "Name 1",
+		"id":   1,
+	}
+
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error selecting: %v", err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("Need 1 record, got %d records", len(r))
+	}
+
+	need := "1, Name 1, Name 1"
+	got := fmt.Sprintf("%d, %s, %s", int(r[0]["id"].(int64)), r[0]["name"], r[0]["password"])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestSelectIn tests select query using a slice parameter in an IN clause.
+func TestSelectIn(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample.sample(name, password) values (?name, ?password) returning id`
+
+	ips := make([]map[string]interface{}, 0)
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 2",
+		"password": "pwd2",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 3",
+		"password": "pwd3",
+	})
+
+	_, err := adapter.QueryBulk(context.Background(), q, ips)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// select
+	q = `select * from sample.sample where id in (?ids) and name <> ?name order by id`
+	params := map[string]interface{}{
+		"ids":  []int{1, 2, 3},
+		"name": "Name 2",
+	}
+
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error selecting: %v", err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("Need 2 records, got %d records", len(r))
+	}
+
+	need := "1, 3"
+	got := fmt.Sprintf("%d, %d", int(r[0]["id"].(int64)), int(r[1]["id"].(int64)))
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// select using an empty slice
+	params = map[string]interface{}{
+		"ids":  []int{},
+		"name": "Name 2",
+	}
+
+	_, err = adapter.Query(context.Background(), q, params)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	eNeed := "postgres-adapter: parameter 'ids' is an empty slice"
+	eGot := err.Error()
+	if eGot != eNeed {
+		t.Errorf("Need `%s`, got `%s`", eNeed, eGot)
+	}
+}
+
+// TestQueryReturnsRows tests queries other than plain selects that return rows.
+func TestQueryReturnsRows(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample.sample(name, password) values (?name, ?password)`
+
+	ips := make([]map[string]interface{}, 0)
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 2",
+		"password": "pwd2",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 3",
+		"password": "pwd3",
+	})
+
+	_, err := adapter.QueryBulk(context.Background(), q, ips)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// select using a common table expression after a comment
+	q = `-- names other than the given one
+		with t as (select * from sample.sample where name <> ?name) select id from t order by id`
+	params := map[string]interface{}{
+		"name": "Name 2",
+	}
+
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error selecting: %v", err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("Need 2 records, got %d records", len(r))
+	}
+
+	// update returning the updated rows
+	q = `update sample.sample set password = ?password where id in (?ids) returning id, password`
+	params = map[string]interface{}{
+		"password": "new",
+		"ids":      []int{1, 2},
+	}
+
+	r, err = adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error updating: %v", err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("Need 2 records, got %d records", len(r))
+	}
+
+	need := "new, new"
+	got := fmt.Sprintf("%v, %v", r[0]["password"], r[1]["password"])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// delete returning the deleted rows
+	q = `delete from sample.sample where id = ?id returning id`
+	params = map[string]interface{}{
+		"id": 3,
+	}
+
+	r, err = adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error deleting: %v", err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("Need 1 record, got %d records", len(r))
+	}
+}
+
+// TestInsertReturningRows tests getting all rows returned by an insert.
+func TestInsertReturningRows(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q := `insert into sample.sample(name, password) values (?name1, ?password), (?name2, ?password) returning id, name`
+	params := map[string]interface{}{
+		"name1":    "Name 1",
+		"name2":    "Name 2",
+		"password": "pwd",
+	}
+
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("Need 2 records, got %d records", len(r))
+	}
+
+	need := "1, Name 1, 2, Name 2"
+	got := fmt.Sprintf("%d, %s, %d, %s", int(r[0]["id"].(int64)), r[0]["name"], int(r[1]["id"].(int64)), r[1]["name"])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	for i, row := range r {
+		aNeed := 2
+		aGot := int(row[internal.AffectedRows].(int64))
+		if aGot != aNeed {
+			t.Errorf("Row %d affected rows: need `%d`, got `%d`", i, aNeed, aGot)
+		}
+
+		iNeed := 2
+		iGot := int(row[internal.LastInsertID].(int64))
+		if iGot != iNeed {
+			t.Errorf("Row %d last insert id: need `%d`, got `%d`", i, iNeed, iGot)
+		}
+	}
+}
+
+// TestReturningNoRows tests the result of a statement having a RETURNING clause that changes no rows.
+func TestReturningNoRows(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q := `update sample.sample set name = 'Name 2' where id = ?id returning id`
+
+	r, err := adapter.Query(context.Background(), q, map[string]interface{}{"id": 1})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("Need 1 record, got %d records", len(r))
+	}
+
+	need := "0, <nil>"
+	got := fmt.Sprintf("%d, %v", r[0][internal.AffectedRows], r[0][internal.LastInsertID])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestQueryEach tests iterating over the rows of a query one at a time.
+func TestQueryEach(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample.sample(name, password) values (?name, ?password)`
+
+	ips := make([]map[string]interface{}, 0)
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 2",
+		"password": "pwd2",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 3",
+		"password": "pwd3",
+	})
+
+	_, err := adapter.QueryBulk(context.Background(), q, ips)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// iterate over all rows inside a transaction
+	q = `select * from sample.sample where id > ?id order by id`
+	params := map[string]interface{}{
+		"id": 0,
+	}
+
+	var names []string
+	_, err = adapter.WrapInTx(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return nil, adapter.QueryEach(ctx, q, params, func(row map[string]interface{}) error {
+			names = append(names, fmt.Sprintf("%s", row["name"]))
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "Name 1, Name 2, Name 3"
+	got := strings.Join(names, ", ")
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// stop early
+	errStop := errors.New("stop")
+	count := 0
+
+	err = adapter.QueryEach(context.Background(), q, params, func(row map[string]interface{}) error {
+		count++
+		if count == 2 {
+			return errStop
+		}
+
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("Need `%v`, got `%v`", errStop, err)
+	}
+	if count != 2 {
+		t.Errorf("Need 2 rows, got %d rows", count)
+	}
+
+	// the adapter should still be usable after stopping early
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(r) != 3 {
+		t.Errorf("Need 3 records, got %d records", len(r))
+	}
+}
+
+// TestQueryCancel tests that a query is aborted when the context is cancelled.
+func TestQueryCancel(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	q := `select pg_sleep(5)`
+
+	start := time.Now()
+
+	_, err := adapter.Query(ctx, q, nil)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := 2 * time.Second
+	got := time.Since(start)
+	if got > need {
+		t.Errorf("Need query to be aborted within %v, took %v", need, got)
+	}
+}
+
 // TestSelectBulk tests bulk select query.
 func TestSelectBulk(t *testing.T) {
 	clearTestTable(t)
@@ -306,6 +705,12 @@ func TestInsertBulk(t *testing.T) {
 		t.Errorf("Last insert id: need `%d`, got `%d`", need, got)
 	}
 
+	idsNeed := "[1 2]"
+	idsGot := fmt.Sprintf("%v", r[0][internal.LastInsertIDs])
+	if idsGot != idsNeed {
+		t.Errorf("Last insert ids: need `%s`, got `%s`", idsNeed, idsGot)
+	}
+
 	// check whether all data is inserted
 	cr, _ := adapter.Query(context.Background(), `select * from sample.sample`, nil)
 	if len(cr) == 0 {
@@ -325,6 +730,45 @@ func TestInsertBulk(t *testing.T) {
 	}
 }
 
+// TestUpdateBulkReturning tests matching the returned ids to the sets of parameters by position.
+func TestUpdateBulkReturning(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	_, err := adapter.QueryBulk(context.Background(), `insert into sample.sample(name, password) values (?name, ?password)`, []map[string]interface{}{
+		{"name": "Name 1", "password": "pwd1"},
+		{"name": "Name 2", "password": "pwd2"},
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	q := `update sample.sample set password = 'changed' where name = ?name returning id`
+
+	r, err := adapter.QueryBulk(context.Background(), q, []map[string]interface{}{
+		{"name": "Name 2"},
+		{"name": "Name 3"},
+		{"name": "Name 1"},
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := 2
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	idsNeed := "[2 <nil> 1]"
+	idsGot := fmt.Sprintf("%v", r[0][internal.LastInsertIDs])
+	if idsGot != idsNeed {
+		t.Errorf("Last insert ids: need `%s`, got `%s`", idsNeed, idsGot)
+	}
+}
+
 // TestUpdateBulk tests bulk update query.
 func TestUpdateBulk(t *testing.T) {
 	clearTestTable(t)
@@ -2,8 +2,12 @@ package postgres_test
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/kosatnkn/db"
 	"github.com/kosatnkn/db/internal"
 )
 
@@ -383,7 +387,8 @@ func TestNestedTxInnerFail(t *testing.T) {
 		t.Fatal("Result type mismatch")
 	}
 
-	need = 0
+	// the outer operation recovers from the failure of the inner operation
+	need = 1
 	got = int(result[0]["count"].(int64))
 	if got != need {
 		t.Errorf("Need %d, got %d", need, got)
@@ -458,3 +463,182 @@ func TestNestedTxOuterFail(t *testing.T) {
 		t.Errorf("Need %d, got %d", need, got)
 	}
 }
+
+// TestNestedTxOuterFailAfterInnerSuccess tests for rolling back of a successful inner operation
+// when the outer operation fails afterwards.
+func TestNestedTxOuterFailAfterInnerSuccess(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	ctx := context.Background()
+
+	q1 := `insert into sample.sample(name, password) values ('Success Data 1', 'pwd1') returning id`
+	q2 := `insert into sample.sample(name, password) values ('Success Data 2', 'pwd2') returning id`
+
+	errOuter := errors.New("outer operation failed")
+
+	// run q1
+	_, err := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+		_, err1 := adapter.Query(ctx, q1, nil)
+		if err1 != nil {
+			return nil, err1
+		}
+
+		// run q2
+		_, err2 := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+			return adapter.Query(ctx, q2, nil)
+		})
+		if err2 != nil {
+			t.Errorf("Error running query 2: %s", err2.Error())
+		}
+
+		// HERE: fail after the inner operation has succeeded
+		return nil, errOuter
+	})
+	if err != errOuter {
+		t.Errorf("Need %v, got %v", errOuter, err)
+	}
+
+	// check whether all data is rolled back
+	r, err := adapter.Query(context.Background(), `select count(*) as count from sample.sample`, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	need := 0
+	got := int(r[0]["count"].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+}
+
+// TestTxOptions tests starting of a transaction using options.
+func TestTxOptions(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	opts := &db.TxOptions{
+		Isolation:  sql.LevelSerializable,
+		ReadOnly:   true,
+		Deferrable: true,
+	}
+
+	q := `select current_setting('transaction_isolation') as isolation,
+		current_setting('transaction_read_only') as read_only,
+		current_setting('transaction_deferrable') as deferrable`
+
+	r, err := adapter.WrapInTxWithOptions(context.Background(), opts, func(ctx context.Context) (interface{}, error) {
+		return adapter.Query(ctx, q, nil)
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	result, ok := r.([]map[string]interface{})
+	if !ok {
+		t.Fatal("Result type mismatch")
+	}
+
+	need := "serializable, on, on"
+	got := fmt.Sprintf("%s, %s, %s", result[0]["isolation"], result[0]["read_only"], result[0]["deferrable"])
+	if need != got {
+		t.Errorf("Need %s, got %s", need, got)
+	}
+}
+
+// TestNestedTxStricterIsolation tests for the failure of a nested transaction requesting a stricter isolation level,
+// or a read only transaction inside one that is not.
+func TestNestedTxStricterIsolation(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	tests := []struct {
+		outer *db.TxOptions
+		inner *db.TxOptions
+		need  string
+	}{
+		{
+			outer: &db.TxOptions{Isolation: sql.LevelRepeatableRead},
+			inner: &db.TxOptions{Isolation: sql.LevelSerializable},
+			need:  "postgres-adapter: cannot start a Serializable transaction inside a Repeatable Read transaction",
+		},
+		{
+			outer: nil,
+			inner: &db.TxOptions{ReadOnly: true},
+			need:  "postgres-adapter: cannot start a read only transaction inside a transaction that is not read only",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := adapter.WrapInTxWithOptions(context.Background(), test.outer, func(ctx context.Context) (interface{}, error) {
+			return adapter.WrapInTxWithOptions(ctx, test.inner, func(ctx context.Context) (interface{}, error) {
+				return nil, nil
+			})
+		})
+		if err == nil {
+			t.Errorf("Need error `%s`, got nil", test.need)
+			continue
+		}
+
+		got := err.Error()
+		if test.need != got {
+			t.Errorf("Need %s, got %s", test.need, got)
+		}
+	}
+}
+
+// TestTxRetry tests retrying of a serializable transaction that fails due to a serialization failure.
+func TestTxRetry(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	opts := &db.TxOptions{Isolation: sql.LevelSerializable}
+	policy := db.RetryPolicy{MaxAttempts: 3}
+
+	qCount := `select count(*) as count from sample.sample`
+	qInsert := `insert into sample.sample(name, password) values (?name, 'pwd') returning id`
+
+	// insertCounted inserts a row named after the number of rows read, making the transaction depend on what it read
+	insertCounted := func(ctx context.Context) (interface{}, error) {
+		r, err := adapter.Query(ctx, qCount, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return adapter.Query(ctx, qInsert, map[string]interface{}{"name": fmt.Sprintf("Count %d", r[0]["count"])})
+	}
+
+	var attempts []int
+
+	_, err := adapter.WrapInTxWithRetry(context.Background(), opts, policy, func(ctx context.Context) (interface{}, error) {
+		attempts = append(attempts, db.TxAttempt(ctx))
+
+		r, err := adapter.Query(ctx, qCount, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// run a conflicting transaction to completion in the middle of the first attempt
+		if db.TxAttempt(ctx) == 1 {
+			_, err := adapter.WrapInTxWithOptions(context.Background(), opts, insertCounted)
+			if err != nil {
+				t.Fatalf("Error running conflicting transaction: %v", err)
+			}
+		}
+
+		return adapter.Query(ctx, qInsert, map[string]interface{}{"name": fmt.Sprintf("Count %d", r[0]["count"])})
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "[1 2]"
+	got := fmt.Sprintf("%v", attempts)
+	if need != got {
+		t.Errorf("Attempts: need %s, got %s", need, got)
+	}
+}
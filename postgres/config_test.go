@@ -0,0 +1,191 @@
+package postgres_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kosatnkn/db"
+	"github.com/kosatnkn/db/postgres"
+)
+
+// validConfig returns a configuration that passes validation.
+func validConfig() postgres.Config {
+	return postgres.Config{
+		Host:     "localhost",
+		Port:     5432,
+		Database: "test",
+		User:     "postgres",
+		Password: "s3cret",
+		PoolSize: 10,
+	}
+}
+
+// TestConfigValidation tests rejecting invalid settings.
+func TestConfigValidation(t *testing.T) {
+	tests := []struct {
+		change func(cfg *postgres.Config)
+		need   string
+	}{
+		{
+			change: func(cfg *postgres.Config) { cfg.Port = 70000 },
+			need:   "postgres-adapter: port should be between 1 and 65535, got 70000",
+		},
+		{
+			change: func(cfg *postgres.Config) { cfg.PoolSize = -1 },
+			need:   "postgres-adapter: pool_size should be greater than 0, got -1",
+		},
+		{
+			change: func(cfg *postgres.Config) { cfg.MaxIdleConns = -1 },
+			need:   "postgres-adapter: max_idle_conns cannot be negative",
+		},
+		{
+			change: func(cfg *postgres.Config) { cfg.MaxIdleConns = 20 },
+			need:   "postgres-adapter: max_idle_conns cannot be greater than pool_size",
+		},
+		{
+			change: func(cfg *postgres.Config) { cfg.ConnectTimeout = -time.Second },
+			need:   "postgres-adapter: connect_timeout cannot be negative",
+		},
+		{
+			change: func(cfg *postgres.Config) { cfg.TLS = db.TLSConfig{Mode: "prefer"} },
+			need:   "postgres-adapter: tls mode 'prefer' is not one of disable, require, verify-ca or verify-full",
+		},
+		{
+			change: func(cfg *postgres.Config) { cfg.TLS = db.TLSConfig{Mode: db.TLSVerifyFull, Cert: "client.crt"} },
+			need:   "postgres-adapter: tls cert and key should be set together",
+		},
+		{
+			change: func(cfg *postgres.Config) { *cfg = postgres.Config{} },
+			need: "postgres-adapter: host is required; port should be between 1 and 65535, got 0; database is required; " +
+				"user is required; pool_size should be greater than 0, got 0",
+		},
+	}
+
+	for _, test := range tests {
+		cfg := validConfig()
+		test.change(&cfg)
+
+		_, err := postgres.NewAdapter(cfg)
+		if err == nil {
+			t.Errorf("Need error `%s`, got nil", test.need)
+			continue
+		}
+
+		got := err.Error()
+		if got != test.need {
+			t.Errorf("Need `%s`, got `%s`", test.need, got)
+		}
+
+		var cfgErr *db.ConfigError
+		if !errors.As(err, &cfgErr) {
+			t.Errorf("Need a *db.ConfigError, got %T", err)
+		}
+	}
+
+	if err := validConfig().Validate(); err != nil {
+		t.Errorf("Need no error, got %v", err)
+	}
+}
+
+// TestConfigString tests redacting the password when formatting a configuration.
+func TestConfigString(t *testing.T) {
+	cfg := validConfig()
+	cfg.Params = map[string]string{"sslpassword": "s3cret"}
+
+	for _, format := range []string{"%s", "%v", "%+v", "%#v"} {
+		got := fmt.Sprintf(format, cfg)
+
+		if strings.Contains(got, "s3cret") {
+			t.Errorf("%s: need the password redacted, got `%s`", format, got)
+		}
+		if !strings.Contains(got, "localhost") {
+			t.Errorf("%s: need the host, got `%s`", format, got)
+		}
+	}
+
+	if cfg.Password != "s3cret" {
+		t.Errorf("Need the password of the config to stay as it is, got `%s`", cfg.Password)
+	}
+}
+
+// TestConfigConnectionString tests passing special characters and params to the driver.
+func TestConfigConnectionString(t *testing.T) {
+	cfg := postgres.Config{
+		Host:     "localhost",
+		Port:     5432,
+		Database: "test",
+		User:     "postgres",
+		Password: `p@ss/w 'o\rd`,
+		PoolSize: 10,
+		Params:   map[string]string{"application_name": "sample app", "search_path": "sample,public"},
+	}
+
+	a, err := postgres.NewAdapter(cfg)
+	if err != nil {
+		t.Fatalf("Need no error, got %v", err)
+	}
+	a.Destruct()
+
+	// params are checked by the driver
+	cfg.Params = map[string]string{"client_encoding": "LATIN1"}
+
+	_, err = postgres.NewAdapter(cfg)
+	if err == nil {
+		t.Errorf("Need error, got nil")
+	}
+}
+
+// TestLoadConfig tests reading the sample configuration overlaid with environment variables.
+func TestLoadConfig(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "password")
+	os.WriteFile(secret, []byte("s3cret\n"), 0600)
+
+	t.Setenv("DB_HOST", "db.example.com")
+	t.Setenv("DB_PASSWORD_FILE", secret)
+
+	cfg, err := postgres.LoadConfig("../config/postgres.yaml")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "db.example.com:5432 test postgres s3cret 10 4m0s sample"
+	got := fmt.Sprintf("%s:%d %s %s %s %d %s %s",
+		cfg.Host, cfg.Port, cfg.Database, cfg.User, cfg.Password, cfg.PoolSize, cfg.ConnMaxIdleTime, cfg.Params["application_name"])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestConfigFromEnv tests rejecting configurations missing required settings.
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("APP_DB_HOST", "localhost")
+	t.Setenv("APP_DB_PORT", "5432")
+	t.Setenv("APP_DB_POOL_SIZE", "10")
+	t.Setenv("APP_DB_DATABASE", "test")
+
+	_, err := postgres.ConfigFromEnv("APP_DB_")
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := "postgres-adapter: user is required"
+	if err.Error() != need {
+		t.Errorf("Need `%s`, got `%s`", need, err.Error())
+	}
+
+	t.Setenv("APP_DB_USER", "postgres")
+
+	cfg, err := postgres.ConfigFromEnv("APP_DB_")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if cfg.User != "postgres" {
+		t.Errorf("Need `postgres`, got `%s`", cfg.User)
+	}
+}
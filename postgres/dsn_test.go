@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+
+	"github.com/kosatnkn/db"
+)
+
+// TestDSN tests that the settings reach the driver as they are.
+func TestDSN(t *testing.T) {
+	cfg := Config{
+		Host:     "localhost",
+		Port:     5432,
+		Database: "test",
+		User:     "postgres",
+		Password: `p@ss/w 'o\rd`,
+		PoolSize: 10,
+		TLS:      db.TLSConfig{Mode: db.TLSVerifyFull, CACert: "/etc/certs/my ca.crt"},
+		Params:   map[string]string{"application_name": "sample app", "search_path": "sample,public"},
+	}
+
+	dsn := cfg.withDefaults().dsn()
+
+	need := `application_name='sample app' connect_timeout=10 dbname=test host=localhost password='p@ss/w \'o\\rd' ` +
+		`port=5432 search_path=sample,public sslmode=verify-full sslrootcert='/etc/certs/my ca.crt' user=postgres`
+	if dsn != need {
+		t.Errorf("Need `%s`, got `%s`", need, dsn)
+	}
+
+	if _, err := pq.NewConnector(dsn); err != nil {
+		t.Errorf("Need the driver to accept the connection string, got %v", err)
+	}
+
+	// params take precedence
+	cfg.Params = map[string]string{"sslmode": "require"}
+
+	need = `connect_timeout=10 dbname=test host=localhost password='p@ss/w \'o\\rd' ` +
+		`port=5432 sslmode=require sslrootcert='/etc/certs/my ca.crt' user=postgres`
+	if got := cfg.withDefaults().dsn(); got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
@@ -0,0 +1,488 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	// database driver for sqlite
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/kosatnkn/db"
+	"github.com/kosatnkn/db/internal"
+)
+
+// Adapter is used to communicate with a SQLite database.
+type Adapter struct {
+	cfg  Config
+	pool *sql.DB
+}
+
+// NewAdapter creates a new SQLite adapter instance.
+func NewAdapter(cfg Config) (db.AdapterInterface, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	// pool configurations
+	db.SetMaxOpenConns(cfg.PoolSize)
+
+	a := &Adapter{
+		cfg:  cfg,
+		pool: db,
+	}
+
+	// check whether the db is accessible
+	if cfg.Check {
+		return a, a.Ping()
+	}
+
+	return a, nil
+}
+
+// Ping checks wether the database is accessible.
+func (a *Adapter) Ping() error {
+	return a.pool.Ping()
+}
+
+// Query runs a query and returns the result.
+func (a *Adapter) Query(ctx context.Context, query string, params map[string]interface{}) ([]map[string]interface{}, error) {
+	convertedQuery, placeholders := a.convertQuery(query, params)
+
+	reorderedParams, err := a.reorderParameters(params, placeholders)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := a.prepareStatement(ctx, convertedQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	st := a.statement(convertedQuery)
+
+	// check whether the query returns rows
+	if st.ReturnsRows() {
+		rows, err := stmt.QueryContext(ctx, reorderedParams...)
+		if err != nil {
+			return nil, err
+		}
+
+		return a.prepareDataSet(rows)
+	}
+
+	result, err := stmt.ExecContext(ctx, reorderedParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.prepareResultSet(result, a.isInsert(st))
+}
+
+// QueryEach runs a query and calls fn with each row of the result one at a time,
+// without holding the whole result in memory.
+//
+// Iteration stops at the first error returned by fn and that error is returned.
+// Inside a transaction fn should not run other queries, since the connection is busy until all rows are read.
+func (a *Adapter) QueryEach(ctx context.Context, query string, params map[string]interface{}, fn func(row map[string]interface{}) error) error {
+	convertedQuery, placeholders := a.convertQuery(query, params)
+
+	reorderedParams, err := a.reorderParameters(params, placeholders)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := a.prepareStatement(ctx, convertedQuery)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, reorderedParams...)
+	if err != nil {
+		return err
+	}
+
+	return a.scanRows(rows, fn)
+}
+
+// QueryBulk runs a query using an array of parameters and return the combined result.
+//
+// This query is intended to do bulk INSERTS, UPDATES and DELETES.
+// Using this for SELECTS will result in an error.
+//
+// For inserts the last insert id of each set of parameters is returned in insertion order as last_insert_ids.
+func (a *Adapter) QueryBulk(ctx context.Context, query string, params []map[string]interface{}) ([]map[string]interface{}, error) {
+	convertedQuery, _ := a.convertQuery(query, nil)
+	st := a.statement(convertedQuery)
+
+	// check whether the query is a select statement
+	if st.Reads() {
+		return nil, fmt.Errorf("sqlite-adapter: select queries are not allowed. use Query() instead")
+	}
+
+	// slice parameters can change the converted query from one set of parameters to the next,
+	// so statements are prepared as and when they are needed
+	stmts := internal.NewStmtCache(func(q string) (*sql.Stmt, error) {
+		return a.prepareStatement(ctx, q)
+	})
+	defer stmts.Close()
+
+	isInsert := a.isInsert(st)
+
+	var lastID int64
+	var affRows int64
+	lastIDs := make([]int64, 0)
+
+	for _, pms := range params {
+		convertedQuery, placeholders := a.convertQuery(query, pms)
+
+		reorderedParams, err := a.reorderParameters(pms, placeholders)
+		if err != nil {
+			return nil, err
+		}
+
+		stmt, err := stmts.Get(convertedQuery)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := stmt.ExecContext(ctx, reorderedParams...)
+		if err != nil {
+			return nil, err
+		}
+
+		if isInsert {
+			lastID, _ = result.LastInsertId()
+			lastIDs = append(lastIDs, lastID)
+		}
+		ar, _ := result.RowsAffected()
+		affRows += ar
+	}
+
+	return a.formatBulkResultSet(lastID, lastIDs, affRows), nil
+}
+
+// WrapInTx runs the content of the function in a single transaction.
+//
+// Nested calls run inside the transaction of the outermost call using savepoints.
+// A failing nested call only rolls back the work done inside it,
+// so that the outer function can recover from the failure.
+//
+// Only the outermost call commits the transaction. An error committing or rolling back is returned as a db.TxError.
+func (a *Adapter) WrapInTx(ctx context.Context, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	return a.WrapInTxWithOptions(ctx, nil, fn)
+}
+
+// WrapInTxWithOptions runs the content of the function in a single transaction started using opts.
+//
+// The default options of the database are used when opts is nil.
+// A nested call requesting a stricter isolation level than the enclosing transaction,
+// or a read only transaction inside one that is not, results in an error.
+func (a *Adapter) WrapInTxWithOptions(ctx context.Context, opts *db.TxOptions, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	// attach a transaction to context
+	ctx, tx, err := a.attachTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// run function
+	res, err := fn(ctx)
+
+	// decide whether to commit or rollback
+	//
+	// Failing to finish the transaction is reported as a db.TxError,
+	// which also carries the error returned by the function if there was one.
+	if err != nil {
+		if txErr := tx.Rollback(); txErr != nil {
+			return nil, &db.TxError{Err: err, TxErr: txErr}
+		}
+
+		return nil, err
+	}
+
+	if txErr := tx.Commit(ctx); txErr != nil {
+		return nil, &db.TxError{TxErr: txErr}
+	}
+
+	return res, nil
+}
+
+// WrapInTxWithRetry runs the content of the function in a single transaction started using opts,
+// running it again when the transaction fails due to the database being locked by another connection.
+//
+// Only the outermost transaction is retried, since a failure at any level rolls back the whole transaction.
+// A nested call behaves the same as WrapInTxWithOptions().
+// Use db.TxAttempt() to get the attempt number inside the function.
+func (a *Adapter) WrapInTxWithRetry(ctx context.Context, opts *db.TxOptions, policy db.RetryPolicy, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	if _, ok := ctx.Value(internal.TxKey).(*internal.Tx); ok {
+		return a.WrapInTxWithOptions(ctx, opts, fn)
+	}
+
+	return internal.Retry(ctx, internal.RetryPolicy(policy), a.isRetryable, func(ctx context.Context) (interface{}, error) {
+		return a.WrapInTxWithOptions(ctx, opts, fn)
+	})
+}
+
+// Destruct will close the SQLite adapter releasing all resources.
+func (a *Adapter) Destruct() error {
+	return a.pool.Close()
+}
+
+// statement finds the kind of the query q.
+func (a *Adapter) statement(q string) internal.Statement {
+	return internal.Classify(q, internal.SQLite)
+}
+
+// isInsert checks whether st is an insert statement.
+//
+// SQLite keeps returning the rowid of the last insert done on a connection for all subsequent statements.
+// This is used to report a last insert id only for inserts the same way MySQL does.
+func (a *Adapter) isInsert(st internal.Statement) bool {
+	return st.Command == "insert" || st.Command == "replace"
+}
+
+// isRetryable checks whether err is caused by the database being busy or locked by another connection.
+func (a *Adapter) isRetryable(err error) bool {
+	// A failure that rolls back the whole transaction also drops its savepoints, so rolling back
+	// the savepoint of a nested call fails as well. errors.As() only looks at that rollback error
+	// of a db.TxError, so the error returned by the wrapped function is checked separately.
+	var txErr *db.TxError
+	if errors.As(err, &txErr) && txErr.Err != nil && a.isRetryable(txErr.Err) {
+		return true
+	}
+
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
+
+// attachTx attaches a database transaction to the context.
+//
+// The transaction is bound to the context, so that it is rolled back when the context is cancelled.
+//
+// This will first check to see whether there is a transaction already in the context.
+// Having a transaction already attached to context probably means that the calling function
+// has been wrapped in a transaction in a previous stage.
+// When this is the case a savepoint is created in the existing attached transaction.
+// Otherwise create a new transaction using opts and attach.
+func (a *Adapter) attachTx(ctx context.Context, opts *db.TxOptions) (context.Context, *internal.Tx, error) {
+	var o internal.TxOptions
+	if opts != nil {
+		o = internal.TxOptions(*opts)
+	}
+
+	// SQLite transactions are always serializable, which satisfies all weaker isolation levels
+	if o.Isolation > sql.LevelSerializable {
+		return nil, nil, fmt.Errorf("sqlite-adapter: isolation level %s is not supported", o.Isolation)
+	}
+
+	if o.ReadOnly {
+		return nil, nil, fmt.Errorf("sqlite-adapter: read only transactions are not supported")
+	}
+
+	if o.Deferrable {
+		return nil, nil, fmt.Errorf("sqlite-adapter: deferrable transactions are not supported")
+	}
+
+	if err := internal.CheckNestedTx(ctx, o); err != nil {
+		return nil, nil, fmt.Errorf("sqlite-adapter: %v", err)
+	}
+
+	return internal.AttachTx(ctx, o, func(ctx context.Context) (*sql.Tx, error) {
+		return a.pool.BeginTx(ctx, nil)
+	})
+}
+
+// convertQuery converts the named parameter query to a placeholder query that SQLite library understands.
+//
+// SQLite placeholder formats look like this.
+//
+// SELECT * FROM tbl WHERE col = ?
+// INSERT INTO tbl(col1, col2, col3) VALUES (?, ?, ?)
+// UPDATE tbl SET col1 = ?, col2 = ? WHERE col3 = ?
+// DELETE FROM tbl WHERE col = ?
+//
+// A named parameter having a slice value in params is expanded to a placeholder for each element.
+//
+// SELECT * FROM tbl WHERE col IN (?vals) -> SELECT * FROM tbl WHERE col IN (?, ?, ?)
+//
+// This will return the query and a slice of strings containing named parameter name in the order that they are found
+// in the query. Named parameters inside string literals, quoted identifiers and comments are left as they are.
+func (a *Adapter) convertQuery(query string, params map[string]interface{}) (string, []string) {
+	query = strings.TrimSpace(query)
+
+	var namedParams []string
+
+	query = internal.ReplaceParams(query, internal.SQLite, func(name string) string {
+		namedParams = append(namedParams, name)
+
+		return a.placeholders(params[name])
+	})
+
+	return query, namedParams
+}
+
+// placeholders returns the placeholders needed to bind the value.
+//
+// A slice value needs a placeholder for each of its elements.
+func (a *Adapter) placeholders(value interface{}) string {
+	elems, ok := internal.ExpandSlice(value)
+	if !ok {
+		return "?"
+	}
+
+	return strings.TrimSuffix(strings.Repeat("?, ", len(elems)), ", ")
+}
+
+// reorderParameters reorders the parameters map in the order of named parameters slice.
+//
+// Slice parameters are expanded in to their elements to match the placeholders created by convertQuery().
+func (a *Adapter) reorderParameters(params map[string]interface{}, namedParams []string) ([]interface{}, error) {
+	var reorderedParams []interface{}
+
+	for _, param := range namedParams {
+		// return an error if a named parameter is missing from params
+		paramValue, ok := params[param]
+		if !ok {
+			return nil, fmt.Errorf("sqlite-adapter: parameter '%s' is missing", param)
+		}
+
+		// expand slices in to individual parameters
+		if elems, ok := internal.ExpandSlice(paramValue); ok {
+			if len(elems) == 0 {
+				return nil, fmt.Errorf("sqlite-adapter: parameter '%s' is an empty slice", param)
+			}
+
+			reorderedParams = append(reorderedParams, elems...)
+			continue
+		}
+
+		reorderedParams = append(reorderedParams, paramValue)
+	}
+
+	return reorderedParams, nil
+}
+
+// prepareStatement creates a prepared statement using the query.
+//
+// Checks whether there is a transaction attached to the context.
+// If so use that transaction to prepare statement else use the pool.
+func (a *Adapter) prepareStatement(ctx context.Context, query string) (*sql.Stmt, error) {
+	if tx, ok := ctx.Value(internal.TxKey).(*internal.Tx); ok {
+		return tx.Tx.PrepareContext(ctx, query)
+	}
+
+	return a.pool.PrepareContext(ctx, query)
+}
+
+// prepareDataSet creates a dataset using the output of a SELECT statement.
+func (a *Adapter) prepareDataSet(rows *sql.Rows) ([]map[string]interface{}, error) {
+	var data []map[string]interface{}
+
+	err := a.scanRows(rows, func(row map[string]interface{}) error {
+		data = append(data, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// scanRows calls fn with each row of the output of a SELECT statement, stopping at the first error returned by fn.
+//
+// Each row is a new map, so fn can keep it.
+//
+// Source: https://kylewbanks.com/blog/query-result-to-map-in-golang
+func (a *Adapter) scanRows(rows *sql.Rows, fn func(row map[string]interface{}) error) error {
+	defer rows.Close()
+
+	cols, _ := rows.Columns()
+
+	// create a slice of interface{}'s to represent each column
+	// and a second slice to contain pointers to each item in the columns slice
+	columns := make([]interface{}, len(cols))
+	columnPointers := make([]interface{}, len(cols))
+
+	for i := range columns {
+		columnPointers[i] = &columns[i]
+	}
+
+	for rows.Next() {
+		// scan the result into the column pointers
+		err := rows.Scan(columnPointers...)
+		if err != nil {
+			return err
+		}
+
+		// create our map, and retrieve the value for each column from the pointers slice
+		// storing it in the map with the name of the column as the key
+		row := make(map[string]interface{})
+
+		for i, colName := range cols {
+			val := columnPointers[i].(*interface{})
+			row[colName] = *val
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	// iteration stops early when an error occurs, as in the case of a cancelled context
+	return rows.Err()
+}
+
+// prepareResultSet creates a resultset using the result of Exec().
+//
+// The last insert id is only read for INSERT statements.
+func (a *Adapter) prepareResultSet(result sql.Result, isInsert bool) ([]map[string]interface{}, error) {
+	var id int64
+	var err error
+
+	if isInsert {
+		id, err = result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	aff, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.formatResultSet(id, aff), nil
+}
+
+// formatResultSet creates a resultset using last insert id and affected rows.
+func (a *Adapter) formatResultSet(id, aff int64) []map[string]interface{} {
+	data := make([]map[string]interface{}, 0)
+
+	return append(data, map[string]interface{}{
+		internal.AffectedRows: aff,
+		internal.LastInsertID: id,
+	})
+}
+
+// formatBulkResultSet creates a resultset using last insert id, the insert ids of all sets of parameters
+// and affected rows.
+func (a *Adapter) formatBulkResultSet(id int64, ids []int64, aff int64) []map[string]interface{} {
+	data := a.formatResultSet(id, aff)
+	data[0][internal.LastInsertIDs] = ids
+
+	return data
+}
@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/kosatnkn/db"
+)
+
+// Config contains database configurations for SQLite database connections.
+type Config struct {
+	// Database is the path to the database file.
+	//
+	// Note: ":memory:" creates a separate in-memory database for each connection in the pool.
+	// Use "file::memory:?cache=shared" to share a single in-memory database among all connections.
+	Database string `yaml:"database"`
+	PoolSize int    `yaml:"pool_size"`
+	Check    bool   `yaml:"check"`
+}
+
+// Validate checks whether the database is set and whether the pool settings are within range.
+//
+// All the problems found are returned at once as a *db.ConfigError.
+func (cfg Config) Validate() error {
+	var problems []string
+
+	if cfg.Database == "" {
+		problems = append(problems, "database is required")
+	}
+
+	// a pool size of 0 lets the pool open any number of connections
+	if cfg.PoolSize < 1 {
+		problems = append(problems, fmt.Sprintf("pool_size should be greater than 0, got %d", cfg.PoolSize))
+	}
+
+	if len(problems) > 0 {
+		return &db.ConfigError{Adapter: "sqlite-adapter", Problems: problems}
+	}
+
+	return nil
+}
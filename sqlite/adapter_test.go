@@ -0,0 +1,841 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kosatnkn/db"
+	"github.com/kosatnkn/db/internal"
+	"github.com/kosatnkn/db/sqlite"
+)
+
+// NOTE: the test database is created in a temporary directory with the following table.
+//
+// | sample 					          |
+// | -------------------------- |
+// | id (int, autoincrement)	  |
+// | name (varchar)				      |
+// | password (varchar) 		    |
+//
+
+// dbFile is the path to the test database file.
+var dbFile string
+
+// TestMain creates the test database before running tests and removes it afterwards.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "sqlite-adapter")
+	if err != nil {
+		fmt.Printf("Cannot create temp dir. Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	dbFile = filepath.Join(dir, "sample.db")
+
+	code := m.Run()
+
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newDBAdapter creates a new db adapter pointing to the test db.
+func newDBAdapter(t *testing.T) db.AdapterInterface {
+	cfg := sqlite.Config{
+		Database: dbFile,
+		PoolSize: 10,
+		Check:    true,
+	}
+
+	a, err := sqlite.NewAdapter(cfg)
+	if err != nil {
+		t.Fatalf("Cannot create adapter. Error: %v", err)
+	}
+
+	return a
+}
+
+// clearTestTable clears all data from the test table.
+func clearTestTable(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q := `create table if not exists sample (
+		id integer primary key autoincrement,
+		name varchar(255),
+		password varchar(255)
+	)`
+
+	_, err := adapter.Query(context.Background(), q, nil)
+	if err != nil {
+		t.Fatalf("Cannot create table. Error: %v", err)
+	}
+
+	_, err = adapter.Query(context.Background(), `delete from sample`, nil)
+	if err != nil {
+		t.Fatalf("Cannot clear table. Error: %v", err)
+	}
+
+	// reset autoincrement
+	_, err = adapter.Query(context.Background(), `delete from sqlite_sequence where name = 'sample'`, nil)
+	if err != nil {
+		t.Fatalf("Cannot reset sequence. Error: %v", err)
+	}
+
+	t.Log("Table truncated")
+}
+
+// TestSelect tests select query.
+func TestSelect(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q := "select * from sample"
+
+	r, err := adapter.Query(context.Background(), q, nil)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := reflect.TypeOf(make([]map[string]interface{}, 0))
+	got := reflect.TypeOf(r)
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+}
+
+// TestInsert tests insert query.
+func TestInsert(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+	params := map[string]interface{}{
+		"name":     "Success Data 1",
+		"password": "pwd1",
+	}
+
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(r) == 0 {
+		t.Errorf("Need 1 record, got %d records", len(r))
+	}
+
+	need := 1
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	need = 1
+	got = int(r[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Last insert id: need `%d`, got `%d`", need, got)
+	}
+
+	// check whether all data is inserted
+	cr, _ := adapter.Query(context.Background(), `select * from sample`, nil)
+	if len(cr) == 0 {
+		t.Errorf("Need 1 record, got %d records", len(cr))
+	}
+
+	cNeed := "1, Success Data 1, pwd1"
+	cGot := fmt.Sprintf("%d, %s, %s", int(cr[0]["id"].(int64)), cr[0]["name"], cr[0]["password"])
+	if cGot != cNeed {
+		t.Errorf("Need `%s`, got `%s`", cNeed, cGot)
+	}
+}
+
+// TestInsertStructParams tests insert query using parameters created from a struct.
+func TestInsertStructParams(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	type sample struct {
+		Name     string `db:"name"`
+		Password string `db:"password"`
+	}
+
+	params, err := db.Params(sample{Name: "Name 1", Password: "pwd1"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	_, err = adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	r, err := db.QueryOne[sample](context.Background(), adapter, `select * from sample`, nil)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "Name 1, pwd1"
+	got := fmt.Sprintf("%s, %s", r.Name, r.Password)
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// a parameter without a matching field
+	params, _ = db.Params(struct {
+		Name string `db:"name"`
+	}{Name: "Name 2"})
+
+	_, err = adapter.Query(context.Background(), q, params)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	eNeed := "sqlite-adapter: parameter 'password' is missing"
+	eGot := err.Error()
+	if eGot != eNeed {
+		t.Errorf("Need `%s`, got `%s`", eNeed, eGot)
+	}
+}
+
+// TestUpdate tests update query.
+func TestUpdate(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample(name, password) values (?name, ?password)`
+	params := map[string]interface{}{
+		"name":     "Success Data 1",
+		"password": "pwd1",
+	}
+
+	_, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	//update
+	q = `update sample set name = ?name, password = ?password where id = ?id`
+	params = map[string]interface{}{
+		"id":       1,
+		"name":     "Success Data 2",
+		"password": "pwd2",
+	}
+
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error updating: %v", err)
+	}
+	if len(r) == 0 {
+		t.Errorf("Need 1 record, got %d records", len(r))
+	}
+
+	need := 1
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	need = 0
+	got = int(r[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Last insert id: need `%d`, got `%d`", need, got)
+	}
+
+	// check whether all data is updated
+	cr, _ := adapter.Query(context.Background(), `select * from sample`, nil)
+	if len(cr) == 0 {
+		t.Errorf("Need 1 record, got %d records", len(cr))
+	}
+
+	cNeed := "1, Success Data 2, pwd2"
+	cGot := fmt.Sprintf("%d, %s, %s", int(cr[0]["id"].(int64)), cr[0]["name"], cr[0]["password"])
+	if cGot != cNeed {
+		t.Errorf("Need `%s`, got `%s`", cNeed, cGot)
+	}
+}
+
+// TestDelete tests delete query.
+func TestDelete(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample(name, password) values (?name, ?password)`
+	params := map[string]interface{}{
+		"name":     "Success Data 1",
+		"password": "pwd1",
+	}
+
+	_, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// delete
+	q = `delete from sample where id = ?id`
+	params = map[string]interface{}{
+		"id": 1,
+	}
+
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error deleting: %v", err)
+	}
+	if len(r) == 0 {
+		t.Errorf("Need 1 record, got %d records", len(r))
+	}
+
+	need := 1
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	need = 0
+	got = int(r[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Last insert id: need `%d`, got `%d`", need, got)
+	}
+
+	// check whether all data is inserted
+	cr, _ := adapter.Query(context.Background(), `select * from sample`, nil)
+	if len(cr) > 0 {
+		t.Errorf("Need 0 record, got %d records", len(cr))
+	}
+}
+
+// TestSelectQuotedPlaceholder tests that named parameters inside strings and comments are not converted.
+func TestSelectQuotedPlaceholder(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample(name, password) values ('?draft', ?password)`
+	params := map[string]interface{}{
+		"password": "pwd1",
+	}
+
+	_, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// select
+	q = `select * from sample where name = '?draft' -- and password = ?password`
+
+	r, err := adapter.Query(context.Background(), q, nil)
+	if err != nil {
+		t.Fatalf("Error selecting: %v", err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("Need 1 record, got %d records", len(r))
+	}
+
+	need := "?draft, pwd1"
+	got := fmt.Sprintf("%s, %s", r[0]["name"], r[0]["password"])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+}
+
+// TestSelectIn tests select query using a slice parameter in an IN clause.
+func TestSelectIn(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	ips := make([]map[string]interface{}, 0)
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 2",
+		"password": "pwd2",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 3",
+		"password": "pwd3",
+	})
+
+	_, err := adapter.QueryBulk(context.Background(), q, ips)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// select
+	q = `select * from sample where id in (?ids) and name <> ?name order by id`
+	params := map[string]interface{}{
+		"ids":  []int{1, 2, 3},
+		"name": "Name 2",
+	}
+
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error selecting: %v", err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("Need 2 records, got %d records", len(r))
+	}
+
+	need := "1, 3"
+	got := fmt.Sprintf("%d, %d", int(r[0]["id"].(int64)), int(r[1]["id"].(int64)))
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// select using an empty slice
+	params = map[string]interface{}{
+		"ids":  []int{},
+		"name": "Name 2",
+	}
+
+	_, err = adapter.Query(context.Background(), q, params)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	eNeed := "sqlite-adapter: parameter 'ids' is an empty slice"
+	eGot := err.Error()
+	if eGot != eNeed {
+		t.Errorf("Need `%s`, got `%s`", eNeed, eGot)
+	}
+}
+
+// TestQueryReturnsRows tests queries other than plain selects that return rows.
+func TestQueryReturnsRows(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	ips := make([]map[string]interface{}, 0)
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 2",
+		"password": "pwd2",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 3",
+		"password": "pwd3",
+	})
+
+	_, err := adapter.QueryBulk(context.Background(), q, ips)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// select using a common table expression after a comment
+	q = `-- names other than the given one
+		with t as (select * from sample where name <> ?name) select id from t order by id`
+	params := map[string]interface{}{
+		"name": "Name 2",
+	}
+
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error selecting: %v", err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("Need 2 records, got %d records", len(r))
+	}
+
+	// update returning the updated rows
+	q = `update sample set password = ?password where id in (?ids) returning id, password`
+	params = map[string]interface{}{
+		"password": "new",
+		"ids":      []int{1, 2},
+	}
+
+	r, err = adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error updating: %v", err)
+	}
+	if len(r) != 2 {
+		t.Fatalf("Need 2 records, got %d records", len(r))
+	}
+
+	need := "new, new"
+	got := fmt.Sprintf("%v, %v", r[0]["password"], r[1]["password"])
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// delete returning the deleted rows
+	q = `delete from sample where id = ?id returning id`
+	params = map[string]interface{}{
+		"id": 3,
+	}
+
+	r, err = adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error deleting: %v", err)
+	}
+	if len(r) != 1 {
+		t.Fatalf("Need 1 record, got %d records", len(r))
+	}
+}
+
+// TestQueryEach tests iterating over the rows of a query one at a time.
+func TestQueryEach(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	ips := make([]map[string]interface{}, 0)
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 2",
+		"password": "pwd2",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 3",
+		"password": "pwd3",
+	})
+
+	_, err := adapter.QueryBulk(context.Background(), q, ips)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// iterate over all rows inside a transaction
+	q = `select * from sample where id > ?id order by id`
+	params := map[string]interface{}{
+		"id": 0,
+	}
+
+	var names []string
+	_, err = adapter.WrapInTx(context.Background(), func(ctx context.Context) (interface{}, error) {
+		return nil, adapter.QueryEach(ctx, q, params, func(row map[string]interface{}) error {
+			names = append(names, fmt.Sprintf("%s", row["name"]))
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "Name 1, Name 2, Name 3"
+	got := strings.Join(names, ", ")
+	if got != need {
+		t.Errorf("Need `%s`, got `%s`", need, got)
+	}
+
+	// stop early
+	errStop := errors.New("stop")
+	count := 0
+
+	err = adapter.QueryEach(context.Background(), q, params, func(row map[string]interface{}) error {
+		count++
+		if count == 2 {
+			return errStop
+		}
+
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("Need `%v`, got `%v`", errStop, err)
+	}
+	if count != 2 {
+		t.Errorf("Need 2 rows, got %d rows", count)
+	}
+
+	// the adapter should still be usable after stopping early
+	r, err := adapter.Query(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(r) != 3 {
+		t.Errorf("Need 3 records, got %d records", len(r))
+	}
+}
+
+// TestQueryCancel tests that a query is aborted when the context is cancelled.
+func TestQueryCancel(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	q := `select count(*) from (
+		with recursive c(x) as (select 1 union all select x + 1 from c where x < 1000000000)
+		select x from c
+	)`
+
+	start := time.Now()
+
+	_, err := adapter.Query(ctx, q, nil)
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := 2 * time.Second
+	got := time.Since(start)
+	if got > need {
+		t.Errorf("Need query to be aborted within %v, took %v", need, got)
+	}
+}
+
+// TestSelectBulk tests bulk select query.
+func TestSelectBulk(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q := "select * from sample"
+
+	_, err := adapter.QueryBulk(context.Background(), q, nil)
+	if err == nil {
+		t.Errorf("Need error, got nil")
+	}
+
+	need := "sqlite-adapter: select queries are not allowed. use Query() instead"
+	got := err.Error()
+	if got != need {
+		t.Errorf("Need %s, got %s", need, got)
+	}
+}
+
+// TestInsertBulk tests bulk insert query.
+func TestInsertBulk(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	params := make([]map[string]interface{}, 0)
+	params = append(params, map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	})
+	params = append(params, map[string]interface{}{
+		"name":     "Name 2",
+		"password": "pwd2",
+	})
+
+	r, err := adapter.QueryBulk(context.Background(), q, params)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(r) == 0 {
+		t.Errorf("Need 1 record, got %d records", len(r))
+	}
+
+	need := 2
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	need = 2
+	got = int(r[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Last insert id: need `%d`, got `%d`", need, got)
+	}
+
+	idsNeed := "[1 2]"
+	idsGot := fmt.Sprintf("%v", r[0][internal.LastInsertIDs].([]int64))
+	if idsGot != idsNeed {
+		t.Errorf("Last insert ids: need `%s`, got `%s`", idsNeed, idsGot)
+	}
+
+	// check whether all data is inserted
+	cr, _ := adapter.Query(context.Background(), `select * from sample`, nil)
+	if len(cr) == 0 {
+		t.Errorf("Need 1 record, got %d records", len(cr))
+	}
+
+	cNeed := "1, Name 1, pwd1"
+	cGot := fmt.Sprintf("%d, %s, %s", int(cr[0]["id"].(int64)), cr[0]["name"], cr[0]["password"])
+	if cGot != cNeed {
+		t.Errorf("Record 1: need `%s`, got `%s`", cNeed, cGot)
+	}
+
+	cNeed = "2, Name 2, pwd2"
+	cGot = fmt.Sprintf("%d, %s, %s", int(cr[1]["id"].(int64)), cr[1]["name"], cr[1]["password"])
+	if cGot != cNeed {
+		t.Errorf("Record 2: need `%s`, got `%s`", cNeed, cGot)
+	}
+}
+
+// TestUpdateBulk tests bulk update query.
+func TestUpdateBulk(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	ips := make([]map[string]interface{}, 0)
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 2",
+		"password": "pwd2",
+	})
+
+	_, err := adapter.QueryBulk(context.Background(), q, ips)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// update
+	q = `update sample set name = ?name, password = ?password where id = ?id`
+
+	ups := make([]map[string]interface{}, 0)
+	ups = append(ups, map[string]interface{}{
+		"id":       1,
+		"name":     "Name 1 Updated",
+		"password": "pwd1 Updated",
+	})
+	ups = append(ups, map[string]interface{}{
+		"id":       2,
+		"name":     "Name 2 Updated",
+		"password": "pwd2 Updated",
+	})
+
+	r, err := adapter.QueryBulk(context.Background(), q, ups)
+	if err != nil {
+		t.Fatalf("Error updating: %v", err)
+	}
+	if len(r) == 0 {
+		t.Errorf("Need 1 record, got %d records", len(r))
+	}
+
+	need := 2
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	need = 0
+	got = int(r[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Last insert id: need `%d`, got `%d`", need, got)
+	}
+
+	// check whether all data is updated
+	cr, _ := adapter.Query(context.Background(), `select * from sample`, nil)
+	if len(cr) != 2 {
+		t.Errorf("Need 2 records, got %d records", len(cr))
+	}
+
+	cNeed := "1, Name 1 Updated, pwd1 Updated"
+	cGot := fmt.Sprintf("%d, %s, %s", int(cr[0]["id"].(int64)), cr[0]["name"], cr[0]["password"])
+	if cGot != cNeed {
+		t.Errorf("Record 1: need `%s`, got `%s`", cNeed, cGot)
+	}
+
+	cNeed = "2, Name 2 Updated, pwd2 Updated"
+	cGot = fmt.Sprintf("%d, %s, %s", int(cr[1]["id"].(int64)), cr[1]["name"], cr[1]["password"])
+	if cGot != cNeed {
+		t.Errorf("Record 2: need `%s`, got `%s`", cNeed, cGot)
+	}
+}
+
+// TestDeleteBulk tests bulk delete query.
+func TestDeleteBulk(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	// insert
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	ips := make([]map[string]interface{}, 0)
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 1",
+		"password": "pwd1",
+	})
+	ips = append(ips, map[string]interface{}{
+		"name":     "Name 2",
+		"password": "pwd2",
+	})
+
+	_, err := adapter.QueryBulk(context.Background(), q, ips)
+	if err != nil {
+		t.Fatalf("Error inserting: %v", err)
+	}
+
+	// delete
+	q = `delete from sample where id = ?id`
+
+	dps := make([]map[string]interface{}, 0)
+	dps = append(dps, map[string]interface{}{
+		"id": 1,
+	})
+	dps = append(dps, map[string]interface{}{
+		"id": 2,
+	})
+
+	r, err := adapter.QueryBulk(context.Background(), q, dps)
+	if err != nil {
+		t.Fatalf("Error deleting: %v", err)
+	}
+	if len(r) == 0 {
+		t.Errorf("Need 1 record, got %d records", len(r))
+	}
+
+	need := 2
+	got := int(r[0][internal.AffectedRows].(int64))
+	if got != need {
+		t.Errorf("Affected rows: need `%d`, got `%d`", need, got)
+	}
+
+	need = 0
+	got = int(r[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Last insert id: need `%d`, got `%d`", need, got)
+	}
+
+	// check whether all data is updated
+	cr, _ := adapter.Query(context.Background(), `select * from sample`, nil)
+	if len(cr) != 0 {
+		t.Errorf("Need 0 records, got %d records", len(cr))
+	}
+}
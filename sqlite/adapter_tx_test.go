@@ -0,0 +1,736 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/kosatnkn/db"
+	"github.com/kosatnkn/db/internal"
+	"github.com/kosatnkn/db/sqlite"
+)
+
+// TestSingleTxSuccess tests for successfull operation of executing multiple queries
+// using the same transaction.
+func TestSingleTxSuccess(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q1 := `insert into sample(name, password) values ('Success Data 1', 'pwd1')`
+	q2 := `insert into sample(name, password) values ('Success Data 2', 'pwd2')`
+	q3 := `insert into sample(name, password) values ('Success Data 3', 'pwd3')`
+
+	r, err := adapter.WrapInTx(context.Background(), func(ctx context.Context) (interface{}, error) {
+
+		r, err := adapter.Query(ctx, q1, nil)
+		if err != nil {
+			return r, err
+		}
+
+		r, err = adapter.Query(ctx, q2, nil)
+		if err != nil {
+			return r, err
+		}
+
+		r, err = adapter.Query(ctx, q3, nil)
+		if err != nil {
+			return r, err
+		}
+
+		return r, err
+	})
+	if err != nil {
+		t.Error("Error running query")
+	}
+
+	result, ok := r.([]map[string]interface{})
+	if !ok {
+		t.Fatal("Result type mismatch")
+	}
+
+	need := 3
+	got := int(result[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+}
+
+// TestSingleTxFail tests for rolling back of the transaction when one query of the
+// list fails.
+func TestSingleTxFail(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	q1 := `insert into sample(name, password) values ('Success Query 1', 'pwd1')`
+	q2 := `insert into non_existant_table(name, password) values ('Data to non existant table', 'pwd')` // failing query
+	q3 := `insert into sample(name, password) values ('Success Query 3', 'pwd3')`
+
+	_, err := adapter.WrapInTx(context.Background(), func(ctx context.Context) (interface{}, error) {
+
+		r, err := adapter.Query(ctx, q1, nil)
+		if err != nil {
+			return r, err
+		}
+
+		r, err = adapter.Query(ctx, q2, nil)
+		if err != nil {
+			return r, err
+		}
+
+		r, err = adapter.Query(ctx, q3, nil)
+		if err != nil {
+			return r, err
+		}
+
+		return r, err
+	})
+	if err == nil {
+		t.Errorf("Need error, got nil")
+	}
+
+	need := `no such table: non_existant_table`
+	got := err.Error()
+	if need != got {
+		t.Errorf("Need %s, got %s", need, got)
+	}
+}
+
+// TestMultipleTxSuccess tests for successfull execution of multiple transactions.
+func TestMultipleTxSuccess(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	ctx := context.Background()
+
+	q1 := `insert into sample(name, password) values ('Success Data 1', 'pwd1')`
+	q2 := `insert into sample(name, password) values ('Success Data 2', 'pwd2')`
+
+	// run q1
+	r, err := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+
+		r, err := adapter.Query(ctx, q1, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return r, err
+	})
+	if err != nil {
+		t.Error("Error running query 1")
+	}
+
+	result, ok := r.([]map[string]interface{})
+	if !ok {
+		t.Fatal("Result type mismatch")
+	}
+
+	need := 1
+	got := int(result[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+
+	// run q2
+	r, err = adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+
+		r, err = adapter.Query(ctx, q2, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return r, err
+	})
+	if err != nil {
+		t.Error("Error running query 2")
+	}
+
+	result, ok = r.([]map[string]interface{})
+	if !ok {
+		t.Fatal("Result type mismatch")
+	}
+
+	need = 2
+	got = int(result[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+
+	// check whether all data is inserted
+	r, err = adapter.Query(context.Background(), `select count(*) as count from sample`, nil)
+	result, ok = r.([]map[string]interface{})
+	if !ok {
+		t.Fatal("Result type mismatch")
+	}
+
+	need = 2
+	got = int(result[0]["count"].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+}
+
+// TestMultipleTxFail tests for multiple transactions in which one of them fails.
+func TestMultipleTxFail(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	ctx := context.Background()
+
+	q1 := `insert into sample(name, password) values (no quotes around this string, 'pwd')` // failing query
+	q2 := `insert into sample(name, password) values ('Success Data 2', 'pwd2')`
+
+	// run q1 (failing query)
+	r, err := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+		r, err := adapter.Query(ctx, q1, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return r, err
+	})
+	if err == nil {
+		t.Errorf("Need error, got nil")
+	}
+
+	errNeed := `near "quotes": syntax error`
+	errGot := err.Error()
+	if errNeed != errGot {
+		t.Errorf("Need %s, got %s", errNeed, errGot)
+	}
+
+	// run q2
+	r, err = adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+		r, err = adapter.Query(ctx, q2, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return r, err
+	})
+	if err != nil {
+		t.Error("Error running query 2")
+	}
+
+	result, ok := r.([]map[string]interface{})
+	if !ok {
+		t.Fatal("Result type mismatch")
+	}
+
+	need := 1
+	got := int(result[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+
+	// check whether all data is inserted
+	r, err = adapter.Query(context.Background(), `select count(*) as count from sample`, nil)
+	result, ok = r.([]map[string]interface{})
+	if !ok {
+		t.Fatal("Result type mismatch")
+	}
+
+	need = 1
+	got = int(result[0]["count"].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+}
+
+// TestNestedTxSuccess tests for successful execution of nested transactions.
+func TestNestedTxSuccess(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	ctx := context.Background()
+
+	q1 := `insert into sample(name, password) values ('Success Data 1', 'pwd1')`
+	q2 := `insert into sample(name, password) values ('Success Data 2', 'pwd2')`
+
+	// run q1
+	r, err := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+		r1, err1 := adapter.Query(ctx, q1, nil)
+		if err1 != nil {
+			return nil, err1
+		}
+
+		// run q2
+		r2, err2 := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+			r2, err2 := adapter.Query(ctx, q2, nil)
+			if err2 != nil {
+				return nil, err2
+			}
+
+			return r2, err2
+		})
+		if err2 != nil {
+			t.Error("Error running query 2")
+		}
+
+		result2, ok2 := r2.([]map[string]interface{})
+		if !ok2 {
+			t.Fatal("Result type mismatch")
+		}
+
+		need2 := 2
+		got2 := int(result2[0][internal.LastInsertID].(int64))
+		if got2 != need2 {
+			t.Errorf("Need %d, got %d", need2, got2)
+		}
+
+		// HERE: return results of q1
+		return r1, err1
+	})
+	if err != nil {
+		t.Errorf("Error running query 1: %s", err.Error())
+	}
+
+	result, ok := r.([]map[string]interface{})
+	if !ok {
+		t.Fatal("Result type mismatch")
+	}
+
+	need := 1
+	got := int(result[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+
+	// check whether all data is inserted
+	r, err = adapter.Query(context.Background(), `select count(*) as count from sample`, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result, ok = r.([]map[string]interface{})
+	if !ok {
+		t.Fatal("Result type mismatch")
+	}
+
+	need = 2
+	got = int(result[0]["count"].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+}
+
+// TestNestedTxInnerFail tests for the failure of inner operation of the nested transactions.
+func TestNestedTxInnerFail(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	ctx := context.Background()
+
+	q1 := `insert into sample(name, password) values ('Success Data 1', 'pwd1')`
+	q2 := `insert into sample(name, password) values (no quotes around this string, 'pwd')` // failing query
+
+	// run q1
+	r, err := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+		r1, err1 := adapter.Query(ctx, q1, nil)
+		if err1 != nil {
+			return nil, err1
+		}
+
+		// run q2
+		_, err2 := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+			r2, err2 := adapter.Query(ctx, q2, nil)
+			if err2 != nil {
+				return nil, err2
+			}
+
+			return r2, err2
+		})
+		if err2 == nil {
+			t.Errorf("Need error, got nil")
+		}
+
+		errNeed := `near "quotes": syntax error`
+		errGot := err2.Error()
+		if errNeed != errGot {
+			t.Errorf("Need %s, got %s", errNeed, errGot)
+		}
+
+		// HERE: return results of q1
+		return r1, err1
+	})
+	if err != nil {
+		t.Errorf("Error running query 1: %s", err.Error())
+	}
+
+	result, ok := r.([]map[string]interface{})
+	if !ok {
+		t.Fatal("Result type mismatch")
+	}
+
+	need := 1
+	got := int(result[0][internal.LastInsertID].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+
+	// check whether all data is inserted
+	r, err = adapter.Query(context.Background(), `select count(*) as count from sample`, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	result, ok = r.([]map[string]interface{})
+	if !ok {
+		t.Fatal("Result type mismatch")
+	}
+
+	// the outer operation recovers from the failure of the inner operation
+	need = 1
+	got = int(result[0]["count"].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+}
+
+// TestNestedTxOuterFail tests for the failure of outer operation of the nested transactions.
+func TestNestedTxOuterFail(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	ctx := context.Background()
+
+	q1 := `insert into sample(name, password) values (no quotes around this string, 'pwd')` // failing query
+	q2 := `insert into sample(name, password) values ('Success Data 2', 'pwd2')`
+
+	// run q1
+	_, err := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+		r1, err1 := adapter.Query(ctx, q1, nil)
+		if err1 != nil {
+			return r1, err1
+		}
+
+		// run q2
+		r2, err2 := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+			r2, err2 := adapter.Query(ctx, q2, nil)
+			if err2 != nil {
+				return r2, err2
+			}
+
+			return r2, err2
+		})
+		if err2 != nil {
+			t.Error("Error running query 2")
+		}
+
+		result2, ok2 := r2.([]map[string]interface{})
+		if !ok2 {
+			t.Fatal("Result type mismatch")
+		}
+
+		need2 := 2
+		got2 := int(result2[0][internal.LastInsertID].(int64))
+		if got2 != need2 {
+			t.Errorf("Need %d, got %d", need2, got2)
+		}
+
+		// HERE: return results of q1
+		return r1, err1
+	})
+	if err == nil {
+		t.Errorf("Need error, got nil")
+	}
+
+	errNeed := `near "quotes": syntax error`
+	errGot := err.Error()
+	if errNeed != errGot {
+		t.Errorf("Need %s, got %s", errNeed, errGot)
+	}
+
+	// check whether all data is inserted
+	r, err := adapter.Query(context.Background(), `select count(*) as count from sample`, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	need := 0
+	got := int(r[0]["count"].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+}
+
+// TestNestedTxOuterFailAfterInnerSuccess tests for rolling back of a successful inner operation
+// when the outer operation fails afterwards.
+func TestNestedTxOuterFailAfterInnerSuccess(t *testing.T) {
+	clearTestTable(t)
+
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	ctx := context.Background()
+
+	q1 := `insert into sample(name, password) values ('Success Data 1', 'pwd1')`
+	q2 := `insert into sample(name, password) values ('Success Data 2', 'pwd2')`
+
+	errOuter := errors.New("outer operation failed")
+
+	// run q1
+	_, err := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+		_, err1 := adapter.Query(ctx, q1, nil)
+		if err1 != nil {
+			return nil, err1
+		}
+
+		// run q2
+		_, err2 := adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+			return adapter.Query(ctx, q2, nil)
+		})
+		if err2 != nil {
+			t.Errorf("Error running query 2: %s", err2.Error())
+		}
+
+		// HERE: fail after the inner operation has succeeded
+		return nil, errOuter
+	})
+	if err != errOuter {
+		t.Errorf("Need %v, got %v", errOuter, err)
+	}
+
+	// check whether all data is rolled back
+	r, err := adapter.Query(context.Background(), `select count(*) as count from sample`, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	need := 0
+	got := int(r[0]["count"].(int64))
+	if got != need {
+		t.Errorf("Need %d, got %d", need, got)
+	}
+}
+
+// TestTxCommitFail tests reporting of an error that occurs when committing the transaction.
+func TestTxCommitFail(t *testing.T) {
+	clearTestTable(t)
+
+	cfg := sqlite.Config{
+		Database: "file:" + dbFile + "?_foreign_keys=on",
+		PoolSize: 1,
+		Check:    true,
+	}
+
+	adapter, err := sqlite.NewAdapter(cfg)
+	if err != nil {
+		t.Fatalf("Cannot create adapter. Error: %v", err)
+	}
+	defer adapter.Destruct()
+
+	ctx := context.Background()
+
+	// foreign key constraints that are deferred are checked only when committing
+	qs := []string{
+		`drop table if exists child`,
+		`create table child (
+			id integer primary key,
+			sample_id integer references sample(id) deferrable initially deferred
+		)`,
+	}
+	for _, q := range qs {
+		_, err := adapter.Query(ctx, q, nil)
+		if err != nil {
+			t.Fatalf("Cannot create table. Error: %v", err)
+		}
+	}
+
+	q := `insert into child(sample_id) values (?id)`
+	params := map[string]interface{}{
+		"id": 1000,
+	}
+
+	_, err = adapter.WrapInTx(ctx, func(ctx context.Context) (interface{}, error) {
+		return adapter.Query(ctx, q, params)
+	})
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	var txErr *db.TxError
+	if !errors.As(err, &txErr) {
+		t.Fatalf("Need a transaction error, got %v", err)
+	}
+
+	need := "commit failed: FOREIGN KEY constraint failed"
+	got := err.Error()
+	if got != need {
+		t.Errorf("Need %s, got %s", need, got)
+	}
+}
+
+// TestTxReadOnly tests for the failure of requesting a read only transaction.
+func TestTxReadOnly(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	opts := &db.TxOptions{ReadOnly: true}
+
+	_, err := adapter.WrapInTxWithOptions(context.Background(), opts, func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	})
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := "sqlite-adapter: read only transactions are not supported"
+	got := err.Error()
+	if need != got {
+		t.Errorf("Need %s, got %s", need, got)
+	}
+}
+
+// TestNestedTxStricterIsolation tests for the failure of a nested transaction requesting a stricter isolation level.
+func TestNestedTxStricterIsolation(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	outer := (*db.TxOptions)(nil)
+	inner := &db.TxOptions{Isolation: sql.LevelSerializable}
+
+	_, err := adapter.WrapInTxWithOptions(context.Background(), outer, func(ctx context.Context) (interface{}, error) {
+		return adapter.WrapInTxWithOptions(ctx, inner, func(ctx context.Context) (interface{}, error) {
+			return nil, nil
+		})
+	})
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := "sqlite-adapter: cannot start a Serializable transaction inside a Default transaction"
+	got := err.Error()
+	if need != got {
+		t.Errorf("Need %s, got %s", need, got)
+	}
+}
+
+// TestTxRetry tests retrying of a transaction that fails because the database is locked by another connection.
+func TestTxRetry(t *testing.T) {
+	clearTestTable(t)
+
+	// fail immediately instead of waiting for the lock to be released
+	cfg := sqlite.Config{
+		Database: "file:" + dbFile + "?_busy_timeout=0",
+		PoolSize: 1,
+		Check:    true,
+	}
+
+	adapter, err := sqlite.NewAdapter(cfg)
+	if err != nil {
+		t.Fatalf("Cannot create adapter. Error: %v", err)
+	}
+	defer adapter.Destruct()
+
+	other := newDBAdapter(t)
+	defer other.Destruct()
+
+	q := `insert into sample(name, password) values (?name, ?password)`
+
+	// hold a write lock using another connection until it is released
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		other.WrapInTx(context.Background(), func(ctx context.Context) (interface{}, error) {
+			r, err := other.Query(ctx, q, map[string]interface{}{"name": "Name 1", "password": "pwd1"})
+			close(locked)
+			<-release
+
+			return r, err
+		})
+	}()
+	<-locked
+
+	policy := db.RetryPolicy{MaxAttempts: 3}
+	params := map[string]interface{}{"name": "Name 2", "password": "pwd2"}
+
+	var attempts []int
+
+	_, err = adapter.WrapInTxWithRetry(context.Background(), nil, policy, func(ctx context.Context) (interface{}, error) {
+		attempts = append(attempts, db.TxAttempt(ctx))
+
+		r, err := adapter.Query(ctx, q, params)
+		if err != nil && db.TxAttempt(ctx) == 1 {
+			// let the other connection finish so that the next attempt succeeds
+			close(release)
+			<-done
+		}
+
+		return r, err
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "[1 2]"
+	got := fmt.Sprintf("%v", attempts)
+	if need != got {
+		t.Errorf("Attempts: need %s, got %s", need, got)
+	}
+
+	r, err := adapter.Query(context.Background(), `select count(*) as count from sample`, nil)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	cNeed := 2
+	cGot := int(r[0]["count"].(int64))
+	if cGot != cNeed {
+		t.Errorf("Need %d, got %d", cNeed, cGot)
+	}
+}
+
+// TestTxRetryFailedRollback tests retrying of a transaction when rolling back a nested transaction failed
+// with an error of its own after the failure that can be retried.
+func TestTxRetryFailedRollback(t *testing.T) {
+	adapter := newDBAdapter(t)
+	defer adapter.Destruct()
+
+	policy := db.RetryPolicy{MaxAttempts: 3}
+
+	var attempts []int
+
+	_, err := adapter.WrapInTxWithRetry(context.Background(), nil, policy, func(ctx context.Context) (interface{}, error) {
+		attempts = append(attempts, db.TxAttempt(ctx))
+
+		if db.TxAttempt(ctx) == 1 {
+			return nil, &db.TxError{
+				Err:   sqlite3.Error{Code: sqlite3.ErrBusy},
+				TxErr: sqlite3.Error{Code: sqlite3.ErrError},
+			}
+		}
+
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	need := "[1 2]"
+	got := fmt.Sprintf("%v", attempts)
+	if need != got {
+		t.Errorf("Attempts: need %s, got %s", need, got)
+	}
+}
@@ -0,0 +1,32 @@
+package sqlite_test
+
+import (
+	"testing"
+
+	"github.com/kosatnkn/db/sqlite"
+)
+
+// TestLoadConfig tests reading the sample configuration overlaid with environment variables.
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("DB_POOL_SIZE", "1")
+
+	cfg, err := sqlite.LoadConfig("../config/sqlite.yaml")
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	if cfg.Database != "sample.db" || cfg.PoolSize != 1 {
+		t.Errorf("Need `sample.db` with a pool size of 1, got `%s` with %d", cfg.Database, cfg.PoolSize)
+	}
+
+	// all problems are reported at once
+	_, err = sqlite.ConfigFromEnv("APP_DB_")
+	if err == nil {
+		t.Fatal("Need error, got nil")
+	}
+
+	need := "sqlite-adapter: database is required; pool_size should be greater than 0, got 0"
+	if err.Error() != need {
+		t.Errorf("Need `%s`, got `%s`", need, err.Error())
+	}
+}